@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"craft/internal/common"
+	"craft/internal/generation"
+	"craft/internal/generator"
+	"craft/internal/manifest"
+	"craft/internal/templating"
+	"craft/internal/utils"
+	"craft/registry"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// NewAddCmd creates a new "add" command that adds a component (e.g. a devcontainer or a database service) to an existing project.
+// Components are templates in templates/components, their files are added like with 'craft new --into' and
+// their patches are merged into existing files of the project.
+func NewAddCmd(templates *templateSource) *cobra.Command {
+	var projectDir string
+	var projectLanguage string
+	var variables []string
+	var conflict string
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "add <component>",
+		Short: "Add a component to an existing project",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("expected exactly one component.\nAvailable components are: %v",
+					strings.Join(componentNames(templates.FS()), ", "))
+			}
+			return nil
+		},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			targetDir, err := filepath.Abs(projectDir)
+			if err != nil {
+				return err
+			}
+
+			ctx, err := projectContext(targetDir, projectLanguage)
+			if err != nil {
+				return err
+			}
+
+			if err := registry.ValidateOperationAndLanguage("add", ctx.Language); err != nil {
+				return err
+			}
+
+			if !utils.Contains(generator.ConflictStrategies, conflict) {
+				return fmt.Errorf("invalid conflict strategy '%s'. Allowed strategies are: %s",
+					conflict, strings.Join(generator.ConflictStrategies, ", "))
+			}
+
+			componentDir := path.Join("templates", manifest.ComponentsDir, args[0])
+			m, err := manifest.Load(templates.FS(), componentDir)
+			if err != nil || !m.IsComponent() {
+				return fmt.Errorf("unknown component '%s'. Available components are: %v",
+					args[0], strings.Join(componentNames(templates.FS()), ", "))
+			}
+			if !m.SupportsLanguage(ctx.Language) {
+				return fmt.Errorf("the component '%s' can only be added to %s projects", m.Name, m.Language)
+			}
+
+			templateVariables, err := parseVariables(variables)
+			if err != nil {
+				return err
+			}
+
+			gen := &generator.Generator{
+				TemplatesFileSystem: templates.FS(),
+				TemplatePath:        componentDir,
+				Context:             ctx,
+				Options: common.Options{
+					Variables: templateVariables,
+					DryRun:    dryRun,
+					Into:      targetDir,
+					Conflict:  conflict,
+				},
+			}
+			return gen.Run()
+		},
+
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().StringVar(&projectDir, "dir", ".", "The project to add the component to")
+	cmd.Flags().StringVarP(&projectLanguage, "language", "l", "", "The language of a project that was generated without a generation manifest (.craft/manifest.json)")
+	cmd.Flags().StringArrayVar(&variables, "set", nil, "Set a variable declared in the component manifest (e.g. --set Database=orders)")
+	cmd.Flags().StringVar(&conflict, "conflict", generator.ConflictAsk, fmt.Sprintf("How to handle files that already exist (%s)", strings.Join(generator.ConflictStrategies, ", ")))
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the files the component would add and patch without touching the disk")
+
+	return cmd
+}
+
+// projectContext returns the template context of the project in projectDir, taken from its generation manifest.
+// Projects without one need the language, the project name is the directory name then.
+func projectContext(projectDir, language string) (templating.Context, error) {
+	if utils.FileExists(generation.Path(projectDir)) {
+		recorded, err := generation.Load(projectDir)
+		if err != nil {
+			return templating.Context{}, err
+		}
+		return recorded.Context(), nil
+	}
+
+	if language == "" {
+		return templating.Context{}, fmt.Errorf("%s has no generation manifest, specify the language of the project with --language", projectDir)
+	}
+	return templating.NewContext(filepath.Base(projectDir), registry.ResolveLanguage(language), nil), nil
+}
+
+// componentNames returns the names of all available components.
+func componentNames(templatesFS fs.FS) []string {
+	discovered, err := manifest.Discover(templatesFS, path.Join("templates", manifest.ComponentsDir))
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, component := range discovered {
+		if component.Manifest.IsComponent() {
+			names = append(names, path.Base(component.Dir))
+		}
+	}
+	return names
+}
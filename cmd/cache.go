@@ -0,0 +1,374 @@
+package cmd
+
+import (
+	"context"
+	"craft/internal/container"
+	"craft/internal/generator"
+	"craft/internal/manifest"
+	"craft/internal/utils"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// NewCacheCmd creates a new "cache" command that manages the generator images craft builds for the containers of its templates
+// and the cached output of these containers. The images are labelled when they are built, images of other tools are never listed or removed.
+func NewCacheCmd(templates *templateSource) *cobra.Command {
+	var runtime string
+
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "List, prune and pre-build the generator images and their cached output",
+	}
+	cmd.PersistentFlags().StringVar(&runtime, "runtime", "", fmt.Sprintf("The container runtime whose images are managed (%s), detected by default", strings.Join(container.Runtimes, ", ")))
+
+	cmd.AddCommand(newCacheListCmd(&runtime))
+	cmd.AddCommand(newCachePruneCmd(&runtime))
+	cmd.AddCommand(newCacheWarmCmd(templates, &runtime))
+	return cmd
+}
+
+func newCacheListCmd(runtime *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "Show the generator images and the cached generator output with their size and last use",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			r, err := optionalRuntime(ctx, *runtime)
+			if err != nil {
+				return err
+			}
+			images, usage, err := cachedImages(ctx, r)
+			if err != nil {
+				return err
+			}
+			outputs, err := cachedOutputs()
+			if err != nil {
+				return err
+			}
+
+			if r != nil && len(images) == 0 {
+				fmt.Println("No generator images found.")
+			} else if len(images) > 0 {
+				writer := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+				fmt.Fprintln(writer, "IMAGE\tTEMPLATE\tSIZE\tLAST USED")
+				var total int64
+				for _, image := range images {
+					fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", image.Name(), image.Template, formatSize(image.Size), formatAge(time.Since(usage.LastUse(image))))
+					total += image.Size
+				}
+				if err := writer.Flush(); err != nil {
+					return err
+				}
+				fmt.Printf("\n%s, %s\n", plural(len(images), "image"), formatSize(total))
+			}
+
+			if len(outputs) > 0 {
+				fmt.Println()
+				writer := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+				fmt.Fprintln(writer, "CACHED OUTPUT\tSIZE\tLAST USED")
+				var total int64
+				for _, output := range outputs {
+					fmt.Fprintf(writer, "%s\t%s\t%s\n", output.Name(), formatSize(output.Size()), formatAge(time.Since(output.ModTime())))
+					total += output.Size()
+				}
+				if err := writer.Flush(); err != nil {
+					return err
+				}
+				fmt.Printf("\n%s in %s, %s\n", plural(len(outputs), "cached output"), filepath.Dir(outputs[0].path), formatSize(total))
+			} else if r == nil {
+				fmt.Println("No cached generator output found.")
+			}
+			return nil
+		},
+	}
+}
+
+func newCachePruneCmd(runtime *string) *cobra.Command {
+	var olderThan string
+	var all bool
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove the generator images and the cached generator output that were not used for a while",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if olderThan == "" && !all {
+				return fmt.Errorf("use --older-than to remove what was not used for a while (e.g. --older-than 30d), or --all to remove everything")
+			}
+			age, err := parseAge(olderThan)
+			if err != nil {
+				return err
+			}
+
+			ctx := context.Background()
+			r, err := optionalRuntime(ctx, *runtime)
+			if err != nil {
+				return err
+			}
+			images, usage, err := cachedImages(ctx, r)
+			if err != nil {
+				return err
+			}
+			outputs, err := cachedOutputs()
+			if err != nil {
+				return err
+			}
+
+			var removed int
+			var freed int64
+			for _, image := range images {
+				if time.Since(usage.LastUse(image)) < age {
+					continue
+				}
+				// a tag moved to a newer build leaves the old image without tags, it is removed by its ID
+				ref := image.ID
+				if len(image.Tags) > 0 {
+					ref = image.Tags[0]
+				}
+				if err := r.RemoveImage(ctx, ref); err != nil {
+					return fmt.Errorf("error removing the image %s: %w", image.Name(), err)
+				}
+				fmt.Printf("Removed %s (%s)\n", image.Name(), formatSize(image.Size))
+				for _, tag := range image.Tags {
+					delete(usage.LastUsed, tag)
+				}
+				removed++
+				freed += image.Size
+			}
+			if removed > 0 {
+				if err := usage.Save(); err != nil {
+					return err
+				}
+			}
+
+			var removedOutputs int
+			for _, output := range outputs {
+				if time.Since(output.ModTime()) < age {
+					continue
+				}
+				if err := os.Remove(output.path); err != nil {
+					return fmt.Errorf("error removing the cached output %s: %w", output.path, err)
+				}
+				fmt.Printf("Removed the cached output %s (%s)\n", output.Name(), formatSize(output.Size()))
+				removedOutputs++
+				freed += output.Size()
+			}
+
+			if removed == 0 && removedOutputs == 0 {
+				fmt.Println("No generator images or cached output to remove.")
+				return nil
+			}
+			fmt.Printf("\n%s and %s removed, %s freed\n", plural(removed, "image"), plural(removedOutputs, "cached output"), formatSize(freed))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&olderThan, "older-than", "", "Remove what was not used for this long, e.g. 30d or 12h")
+	cmd.Flags().BoolVar(&all, "all", false, "Remove all generator images and the whole cached output")
+	cmd.MarkFlagsMutuallyExclusive("older-than", "all")
+	return cmd
+}
+
+func newCacheWarmCmd(templates *templateSource, runtime *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "warm [template...]",
+		Short: "Build the generator images ahead of time, for all templates or the named ones",
+		Long:  "Build the generator images of the templates ahead of time, so 'craft new' only rebuilds the steps that depend on the project. Templates are named like in 'craft inspect', e.g. java-maven-quarkus.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			discovered, err := manifest.Discover(templates.FS(), "templates")
+			if err != nil {
+				return err
+			}
+
+			var selected []manifest.Discovered
+			found := map[string]bool{}
+			for _, template := range discovered {
+				if len(args) > 0 && !utils.Contains(args, template.Manifest.Name) {
+					continue
+				}
+				found[template.Manifest.Name] = true
+				if len(template.Manifest.Containers) > 0 {
+					selected = append(selected, template)
+				}
+			}
+			for _, name := range args {
+				if !found[name] {
+					return fmt.Errorf("unknown template '%s', 'craft inspect' lists the available templates", name)
+				}
+			}
+			if len(selected) == 0 {
+				fmt.Println("None of the templates builds generator images.")
+				return nil
+			}
+
+			ctx := context.Background()
+			r, err := availableRuntime(ctx, *runtime)
+			if err != nil {
+				return err
+			}
+
+			for _, template := range selected {
+				fmt.Printf("Warming the images of %s\n", template.Manifest.Name)
+				gen := &generator.Generator{TemplatesFileSystem: templates.FS(), TemplatePath: template.Dir}
+				if err := gen.Warm(ctx, r); err != nil {
+					return fmt.Errorf("error warming the images of %s: %w", template.Manifest.Name, err)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// availableRuntime returns the runtime of --runtime or the detected one, once it answered.
+func availableRuntime(ctx context.Context, name string) (container.Runtime, error) {
+	runtime, err := container.Detect(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := runtime.Ping(ctx); err != nil {
+		return nil, err
+	}
+	return runtime, nil
+}
+
+// optionalRuntime returns the runtime like availableRuntime, or nil if no runtime was chosen with --runtime and none is available.
+// The cached output can be listed and pruned without one.
+func optionalRuntime(ctx context.Context, name string) (container.Runtime, error) {
+	runtime, err := availableRuntime(ctx, name)
+	if err != nil && name == "" && container.IsUnavailable(err) {
+		fmt.Printf("No container runtime is available (%v), the generator images are left out\n", err)
+		return nil, nil
+	}
+	return runtime, err
+}
+
+// cachedImages returns the generator images of the runtime, the least recently used first, and their usage.
+// Without a runtime there are no images.
+func cachedImages(ctx context.Context, runtime container.Runtime) ([]container.Image, *container.Usage, error) {
+	var images []container.Image
+	if runtime != nil {
+		var err error
+		if images, err = runtime.Images(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	usageFile, err := container.DefaultUsageFile()
+	if err != nil {
+		return nil, nil, err
+	}
+	usage, err := container.LoadUsage(usageFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sort.Slice(images, func(i, j int) bool { return usage.LastUse(images[i]).Before(usage.LastUse(images[j])) })
+	return images, usage, nil
+}
+
+// cachedOutput is an archive with the output of a cached container, see generator.DefaultOutputCacheDir.
+// Its modification time is its last use.
+type cachedOutput struct {
+	os.FileInfo
+	path string
+}
+
+// cachedOutputs returns the archives of the cached generator output, the least recently used first.
+func cachedOutputs() ([]cachedOutput, error) {
+	cacheDir, err := generator.DefaultOutputCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(cacheDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading the cache directory %s: %w", cacheDir, err)
+	}
+
+	var outputs []cachedOutput
+	for _, entry := range entries {
+		// archives that are still written start with a dot
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".tar.gz") || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, cachedOutput{FileInfo: info, path: filepath.Join(cacheDir, entry.Name())})
+	}
+
+	sort.Slice(outputs, func(i, j int) bool { return outputs[i].ModTime().Before(outputs[j].ModTime()) })
+	return outputs, nil
+}
+
+// parseAge parses a duration like 12h or 90m, which may also be given in days: 30d
+func parseAge(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age '%s', use e.g. 30d or 12h", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age '%s', use e.g. 30d or 12h", value)
+	}
+	return age, nil
+}
+
+// formatSize returns the size in bytes like the runtimes print it, e.g. 512MB or 1.2GB.
+func formatSize(size int64) string {
+	const unit = 1000
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	value, suffix := float64(size)/unit, "kB"
+	for _, next := range []string{"MB", "GB", "TB"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, next
+	}
+	if value < 10 {
+		return fmt.Sprintf("%.1f%s", value, suffix)
+	}
+	return fmt.Sprintf("%.0f%s", value, suffix)
+}
+
+// formatAge returns how long ago something happened in the largest fitting unit, e.g. "3 days ago".
+func formatAge(age time.Duration) string {
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return plural(int(age.Minutes()), "minute") + " ago"
+	case age < 24*time.Hour:
+		return plural(int(age.Hours()), "hour") + " ago"
+	default:
+		return plural(int(age.Hours()/24), "day") + " ago"
+	}
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
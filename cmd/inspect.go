@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"craft/internal/manifest"
 	"craft/registry"
 	"fmt"
 
@@ -9,14 +10,14 @@ import (
 	"golang.org/x/text/language"
 )
 
-// NewInspectCmd creates a new "inspect" command that displays allowed operations and languages.
-func NewInspectCmd() *cobra.Command {
+// NewInspectCmd creates a new "inspect" command that displays allowed operations, languages and the available templates.
+func NewInspectCmd(templates *templateSource) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "inspect",
-		Short: "Show allowed operations and languages",
+		Short: "Show allowed operations, languages and templates",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			showAllowedOptions()
-			return nil
+			return showTemplates(templates)
 		},
 	}
 	return cmd
@@ -36,3 +37,32 @@ func showAllowedOptions() {
 		}
 	}
 }
+
+func showTemplates(templates *templateSource) error {
+	discovered, err := manifest.Discover(templates.FS(), "templates")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\nAvailable Templates:")
+	for _, template := range discovered {
+		if !template.Manifest.IsComponent() {
+			showTemplate(templates, template)
+		}
+	}
+
+	fmt.Println("\nAvailable Components (craft add <component>):")
+	for _, template := range discovered {
+		if template.Manifest.IsComponent() {
+			showTemplate(templates, template)
+		}
+	}
+	return nil
+}
+
+func showTemplate(templates *templateSource, template manifest.Discovered) {
+	fmt.Printf("- %s (%s) from %s\n", template.Manifest.Name, template.Manifest.Language, templates.Source(template.Dir))
+	if template.Manifest.Description != "" {
+		fmt.Printf("    %s\n", template.Manifest.Description)
+	}
+}
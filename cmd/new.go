@@ -1,14 +1,25 @@
 package cmd
 
 import (
+	"craft/internal/common"
 	"craft/internal/constants"
+	"craft/internal/container"
+	"craft/internal/generator"
 	"craft/internal/handlers"
+	"craft/internal/manifest"
+	"craft/internal/utils"
 	"craft/registry"
-	"embed"
 	"fmt"
+	"io/fs"
+	"path/filepath"
 	"strings"
 
+	cpphandler "craft/internal/handlers/cpp"
+	gohandler "craft/internal/handlers/go"
 	javahandler "craft/internal/handlers/java"
+	pythonhandler "craft/internal/handlers/python"
+	rusthandler "craft/internal/handlers/rust"
+	typescripthandler "craft/internal/handlers/typescript"
 
 	"github.com/spf13/cobra"
 	"golang.org/x/text/cases"
@@ -17,21 +28,33 @@ import (
 
 // NewNewCmd creates a new "new" command to generate a project scaffold for a specified language.
 // It supports specifying a project name or using the current directory as the project name.
-// Templates for the project are embedded in the provided templatesFS parameter.
-func NewNewCmd(templatesFS embed.FS) *cobra.Command {
+// Templates for the project are taken from the provided templates (built-in and user template directories).
+func NewNewCmd(templates *templateSource) *cobra.Command {
 	var specifiedProjectName string
 	var dependencies string
-
-	allowedLanguages := registry.GetAllowedLanguages("new")
-	allowedLanguagesText := strings.Join(allowedLanguages, ", ")
+	var variables []string
+	var from string
+	var dryRun bool
+	var keepFailed bool
+	var into string
+	var conflict string
+	var modulePath string
+	var groupId string
+	var javaPackage string
+	var javaVersion string
+	var modules string
+	var runtime string
+	var noDocker bool
+	var noCache bool
 
 	cmd := &cobra.Command{
 		Use:   "new <language>",
 		Short: "Create a new project",
 		Args: func(cmd *cobra.Command, args []string) error {
-			if len(args) < 1 {
+			// a repository containing a single template defines the language itself
+			if len(args) < 1 && from == "" {
 				return fmt.Errorf("missing required argument: <language>.\nSupported languages are: %v",
-					allowedLanguagesText)
+					strings.Join(registry.GetAllowedLanguages("new"), ", "))
 			}
 			if len(args) > 1 {
 				return fmt.Errorf("unexpected additional arguments: %v\n\n%s", args[1:], cmd.UsageString())
@@ -48,20 +71,16 @@ func NewNewCmd(templatesFS embed.FS) *cobra.Command {
 				}
 
 				titleCaser := cases.Title(language.English) // Proper Unicode casing
-				language := args[0]
-				dependenciesInfo := fetchSupportedDependenciesInfo(language, titleCaser)
+				language := registry.ResolveLanguage(args[0])
+				dependenciesInfo := fetchSupportedDependenciesInfo(templates.FS(), language, titleCaser)
 				fmt.Println(dependenciesInfo)
 				return nil
 			}
-			language := args[0]
-
-			err := registry.ValidateOperationAndLanguage("new", language)
-			if err != nil {
-				return err
+			var language string
+			if len(args) > 0 {
+				language = registry.ResolveLanguage(args[0])
 			}
 
-			projectName := getProjectDetails(specifiedProjectName, language)
-
 			rawDeps := strings.Split(dependencies, ",")
 			deps := make([]string, 0, len(rawDeps))
 
@@ -72,12 +91,77 @@ func NewNewCmd(templatesFS embed.FS) *cobra.Command {
 				}
 			}
 
-			handler, err := handlers.GetNewHandler(strings.ToLower(language), deps)
+			templateVariables, err := parseVariables(variables)
 			if err != nil {
 				return err
 			}
 
-			handler.SetTemplatesFS(&templatesFS)
+			if !utils.Contains(generator.ConflictStrategies, conflict) {
+				return fmt.Errorf("invalid conflict strategy '%s'. Allowed strategies are: %s",
+					conflict, strings.Join(generator.ConflictStrategies, ", "))
+			}
+
+			if runtime != "" && !utils.Contains(container.Runtimes, runtime) {
+				return fmt.Errorf("invalid container runtime '%s'. Allowed runtimes are: %s",
+					runtime, strings.Join(container.Runtimes, ", "))
+			}
+
+			if runtime != "" && noDocker {
+				return fmt.Errorf("--runtime and --no-docker can not be used together")
+			}
+
+			options := common.Options{
+				Variables:  templateVariables,
+				DryRun:     dryRun,
+				KeepFailed: keepFailed,
+				Into:       into,
+				Conflict:   conflict,
+				ModulePath: modulePath,
+				Runtime:    runtime,
+				NoDocker:   noDocker,
+				NoCache:    noCache,
+			}
+
+			var handler common.NewHandler
+			if from != "" {
+				handler, language, options.TemplateSource, err = templates.fetch(from, language, deps)
+				if err != nil {
+					return err
+				}
+			}
+
+			// the language of a template from a git repository is only known once it was fetched
+			if modulePath != "" && language != "go" {
+				return fmt.Errorf("--module can only be used for go projects")
+			}
+
+			// the java options are template variables, they take precedence over the same variables given with --set
+			javaVariables := map[string]string{"GroupId": groupId, "Package": javaPackage, "JavaVersion": javaVersion, "Modules": modules}
+			for name, value := range javaVariables {
+				if value == "" {
+					continue
+				}
+				if language != "java" {
+					return fmt.Errorf("--group-id, --package, --java-version and --modules can only be used for java projects")
+				}
+				options.Variables[name] = value
+			}
+
+			if handler == nil {
+				if err := registry.ValidateOperationAndLanguage("new", language); err != nil {
+					return err
+				}
+
+				handler, err = handlers.GetNewHandler(language, deps)
+				if err != nil {
+					return err
+				}
+				handler.SetTemplatesFS(templates.FS())
+			}
+
+			projectName := getProjectDetails(specifiedProjectName, language, into)
+
+			handler.SetOptions(options)
 			err = handler.Run(projectName)
 			if err != nil {
 				return err
@@ -92,29 +176,65 @@ func NewNewCmd(templatesFS embed.FS) *cobra.Command {
 	cmd.Flags().StringVarP(&dependencies, "dependencies", "d", "", "Specify the dependencies or project type (e.g., -d maven-spring)")
 	cmd.Flags().StringVarP(&specifiedProjectName, "name", "n", "", "Specify the project name (e.g. -n my-test-project)")
 	cmd.Flags().Bool("show-dependencies", false, "Show supported dependencies for the specified language")
+	cmd.Flags().StringArrayVar(&variables, "set", nil, "Set a template variable declared in the template manifest (e.g. --set Port=8080)")
+	cmd.Flags().StringVar(&from, "from", "", "Use the templates of a git repository pinned to a tag or commit (e.g. --from git+https://github.com/org/templates.git#v1.2.0)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the files, scripts and docker images of the generation without touching the disk")
+	cmd.Flags().BoolVar(&keepFailed, "keep-failed", false, "Keep the staging directory of a failed generation for debugging")
+	cmd.Flags().StringVar(&into, "into", "", "Generate the files into an existing directory instead of a new project directory (e.g. --into .)")
+	cmd.Flags().StringVar(&modulePath, "module", "", "The module path of a go project (e.g. --module github.com/org/my-project), defaults to the modulePrefix of the user configuration plus the project name")
+	cmd.Flags().StringVar(&groupId, "group-id", "", "The maven groupId of a java project (e.g. --group-id com.example)")
+	cmd.Flags().StringVar(&javaPackage, "package", "", "The java package of a java project, defaults to the groupId (e.g. --package com.example.orders)")
+	cmd.Flags().StringVar(&javaVersion, "java-version", "", "The java version of a java project (17, 21 or 25), defaults to 21")
+	cmd.Flags().StringVar(&modules, "modules", "", "The comma separated modules of a multi-module maven project, the last one is the application (e.g. -d multimodule --modules api,core,app)")
+	cmd.Flags().StringVar(&runtime, "runtime", "", fmt.Sprintf("The container runtime that generates the project and that the generated files use (%s), detected by default", strings.Join(container.Runtimes, ", ")))
+	cmd.Flags().BoolVar(&noDocker, "no-docker", false, "Render the built-in skeletons of the templates instead of running their generator containers, no container runtime is needed")
+	cmd.Flags().BoolVar(&noCache, "no-cache", false, "Run the generator containers of the templates even if their output is cached (and cache it again)")
+	cmd.Flags().StringVar(&conflict, "conflict", generator.ConflictAsk, fmt.Sprintf("How to handle files that already exist with --into (%s)", strings.Join(generator.ConflictStrategies, ", ")))
 
 	return cmd
 }
 
-func getProjectDetails(specifiedProjectName, language string) string {
+func getProjectDetails(specifiedProjectName, language, into string) string {
 	if specifiedProjectName != "" {
 		return specifiedProjectName
 	}
 
+	// the existing directory already names the project
+	if into != "" {
+		if dir, err := filepath.Abs(into); err == nil {
+			return filepath.Base(dir)
+		}
+	}
+
 	return fmt.Sprintf("%v-%v", constants.ToolName, language)
 }
 
-func fetchSupportedDependenciesInfo(language string, titleCaser cases.Caser) string {
+// parseVariables turns the "key=value" pairs of the --set flag into a map.
+func parseVariables(pairs []string) (map[string]string, error) {
+	variables := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, found := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid variable '%s', expected the format key=value", pair)
+		}
+		variables[key] = value
+	}
+	return variables, nil
+}
+
+func fetchSupportedDependenciesInfo(templatesFS fs.FS, language string, titleCaser cases.Caser) string {
 	language = strings.ToLower(language)
 	switch language {
 	case "java":
 		// Java-specific logic
 		combinations := javahandler.GetAllowedCombinations()
 		var sb strings.Builder
-		sb.WriteString("Maven is the default build-tool for the java projects:\n\n")
+		sb.WriteString("Maven is the default build-tool for the java projects, Gradle (Kotlin DSL) is used with -d gradle:\n\n")
 
 		sb.WriteString("Supported Dependencies:\n")
-		for buildTool, frameworks := range combinations {
+		for _, buildTool := range utils.SortedKeys(combinations) {
+			frameworks := combinations[buildTool]
 			sb.WriteString(fmt.Sprintf("  for the build tool '%s' are:\n", buildTool))
 			if len(frameworks) == 0 || (len(frameworks) == 1 && frameworks[0] == "") {
 				sb.WriteString("    - No specific frameworks required\n")
@@ -128,11 +248,81 @@ func fetchSupportedDependenciesInfo(language string, titleCaser cases.Caser) str
 				}
 			}
 		}
+		sb.WriteString(fmt.Sprintf("\nMaven projects are split into modules with -d multimodule (with %s or without a framework), the modules are named with --modules (default api,core,app)\n",
+			strings.Join(javahandler.GetAllowedMultiModuleFrameworks()[1:], ", ")))
+		return sb.String()
+	case "python":
+		packageManagers := pythonhandler.GetAllowedPackageManagers()
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("%s is the default package manager for the python projects:\n\n", titleCaser.String(packageManagers[0])))
+
+		sb.WriteString("Supported Dependencies (one package manager):\n")
+		for _, packageManager := range packageManagers {
+			sb.WriteString(fmt.Sprintf("  - %s\n", packageManager))
+		}
+		return sb.String()
+	case "typescript":
+		combinations := typescripthandler.GetAllowedCombinations()
+		var sb strings.Builder
+		sb.WriteString("npm is the default package manager for the typescript projects, without a framework a plain node application is created:\n\n")
+
+		sb.WriteString("Supported Dependencies:\n")
+		for _, packageManager := range utils.SortedKeys(combinations) {
+			sb.WriteString(fmt.Sprintf("  for the package manager '%s' are:\n", packageManager))
+			for _, framework := range combinations[packageManager] {
+				if framework != "" {
+					sb.WriteString(fmt.Sprintf("    - %s\n", framework))
+				}
+			}
+		}
+		return sb.String()
+	case "cpp", "c":
+		testFrameworks := cpphandler.GetAllowedTestFrameworks()
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("%s is the default test framework for the %s projects (CMake):\n\n", testFrameworks[0], language))
+
+		sb.WriteString("Supported Dependencies (one test framework):\n")
+		for _, testFramework := range testFrameworks {
+			sb.WriteString(fmt.Sprintf("  - %s\n", testFramework))
+		}
+		return sb.String()
+	case "rust":
+		combinations := rusthandler.GetAllowedCombinations()
+		var sb strings.Builder
+		sb.WriteString("Without a kind a binary crate (bin) is created for the rust projects, the stacks can be combined (axum brings tokio):\n\n")
+
+		sb.WriteString("Supported Dependencies (one kind):\n")
+		for _, kind := range utils.SortedKeys(combinations) {
+			sb.WriteString(fmt.Sprintf("  - %s with the stacks: %s\n", kind, strings.Join(combinations[kind], ", ")))
+		}
 		return sb.String()
 	case "go":
-		// Go-specific logic (no dependencies)
-		return "Supported Dependencies:\n  - None supported\n"
+		combinations := gohandler.GetAllowedCombinations()
+		var sb strings.Builder
+		sb.WriteString("Without a kind a module with a main.go (app) is created for the go projects, chi implies the http kind:\n\n")
+
+		sb.WriteString("Supported Dependencies (one kind):\n")
+		for _, kind := range utils.SortedKeys(combinations) {
+			sb.WriteString(fmt.Sprintf("  - %s\n", kind))
+			for _, framework := range combinations[kind] {
+				if framework != "" {
+					sb.WriteString(fmt.Sprintf("    - with the framework %s\n", framework))
+				}
+			}
+		}
+		return sb.String()
 	default:
-		return "No supported dependencies for this language."
+		// Languages without a dedicated handler declare their dependencies in the template manifest
+		m, err := manifest.Load(templatesFS, filepath.Join("templates", language))
+		if err != nil || len(m.Dependencies) == 0 {
+			return "No supported dependencies for this language."
+		}
+
+		var sb strings.Builder
+		sb.WriteString("Supported Dependencies:\n")
+		for _, dependency := range m.Dependencies {
+			sb.WriteString(fmt.Sprintf("  - %s\n", dependency))
+		}
+		return sb.String()
 	}
 }
@@ -1,20 +1,32 @@
 package cmd
 
 import (
+	"craft/internal/constants"
 	"embed"
 
 	"github.com/spf13/cobra"
 )
 
 func NewRootCmd(templatesFS embed.FS) *cobra.Command {
+	templates := &templateSource{embedded: templatesFS}
+
 	rootCmd := &cobra.Command{
-		Use:   "craft",
-		Short: "A CLI tool to help bootstrap new Projects ",
-		Long:  "This tool helps create new projects quickly by generating boilerplate code for a specified language or framework. Everything is configured to ensure the project runs seamlessly in a Docker container. Run craft help for more details.",
+		Use:     "craft",
+		Version: constants.Version,
+		Short:   "A CLI tool to help bootstrap new Projects ",
+		Long:    "This tool helps create new projects quickly by generating boilerplate code for a specified language or framework. Everything is configured to ensure the project runs seamlessly in a Docker container. Run craft help for more details.",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return templates.load()
+		},
 	}
 
-	rootCmd.AddCommand(NewNewCmd(templatesFS))
-	rootCmd.AddCommand(NewInspectCmd())
+	rootCmd.PersistentFlags().StringVar(&templates.templateDir, "template-dir", "", "Use the templates in this directory in addition to the built-in ones (e.g. --template-dir ./my-templates)")
+
+	rootCmd.AddCommand(NewNewCmd(templates))
+	rootCmd.AddCommand(NewInspectCmd(templates))
+	rootCmd.AddCommand(NewUpdateCmd(templates))
+	rootCmd.AddCommand(NewAddCmd(templates))
+	rootCmd.AddCommand(NewCacheCmd(templates))
 
 	return rootCmd
 }
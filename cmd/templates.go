@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"craft/internal/common"
+	"craft/internal/constants"
+	"craft/internal/generation"
+	"craft/internal/gitsource"
+	generichandler "craft/internal/handlers/generic"
+	"craft/internal/manifest"
+	"craft/internal/templatefs"
+	"craft/internal/updater"
+	"craft/registry"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// templateSource provides the templates of all commands. The layers are only known after the flags were parsed:
+// templates from --template-dir shadow the ones in the user config directory (~/.config/craft/templates),
+// which shadow the templates embedded into the binary.
+type templateSource struct {
+	embedded    fs.FS
+	templateDir string
+	layers      []templatefs.Layer
+	layered     *templatefs.Layered
+}
+
+// load builds the layered filesystem and allows the 'new' operation for every language that has a template manifest.
+func (s *templateSource) load() error {
+	layers := []templatefs.Layer{}
+
+	if s.templateDir != "" {
+		dirFS, err := templatefs.MountDir("templates", s.templateDir)
+		if err != nil {
+			return err
+		}
+		layers = append(layers, templatefs.Layer{Name: s.templateDir, FS: dirFS})
+	}
+
+	if userDir := userTemplateDir(); userDir != "" {
+		if dirFS, err := templatefs.MountDir("templates", userDir); err == nil {
+			layers = append(layers, templatefs.Layer{Name: userDir, FS: withoutInvalidTemplates(dirFS, userDir)})
+		}
+	}
+
+	layers = append(layers, templatefs.Layer{Name: "built-in", FS: s.embedded})
+	s.layers = layers
+	s.layered = templatefs.NewLayered(layers...)
+
+	return registerTemplateLanguages(s.layered)
+}
+
+// fetch checks out a template repository (--from git+<url>#<ref>) from the cache or the remote.
+//
+// A repository with a manifest in its root is a single template: the returned handler generates it and the
+// language is taken from the manifest. A repository with a templates directory (same layout as the built-in
+// templates) is added as the layer with the highest priority, no handler is returned in that case.
+func (s *templateSource) fetch(from, language string, dependencies []string) (common.NewHandler, string, *common.TemplateSource, error) {
+	source, err := gitsource.Parse(from)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	cacheDir, err := gitsource.DefaultCacheDir()
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	checkout, err := gitsource.Fetch(source, cacheDir)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	templateSource := &common.TemplateSource{URL: source.URL, Ref: source.Ref, Commit: checkout.Commit}
+	checkoutFS := os.DirFS(checkout.Dir)
+
+	if _, err := fs.Stat(checkoutFS, manifest.FileName); err == nil {
+		m, err := manifest.Load(checkoutFS, ".")
+		if err != nil {
+			return nil, "", nil, err
+		}
+		if language != "" && !strings.EqualFold(language, m.Language) {
+			return nil, "", nil, fmt.Errorf("the template '%s' from %s is a '%s' template, not '%s'", m.Name, source, m.Language, language)
+		}
+
+		handler := &generichandler.NewGenericHandler{
+			Language:            strings.ToLower(m.Language),
+			Dependencies:        dependencies,
+			TemplatesFileSystem: checkoutFS,
+			TemplatePath:        ".",
+		}
+		return handler, handler.Language, templateSource, nil
+	}
+
+	if _, err := fs.Stat(checkoutFS, "templates"); err != nil {
+		return nil, "", nil, fmt.Errorf("%s contains neither a %s nor a templates directory", source, manifest.FileName)
+	}
+	if language == "" {
+		return nil, "", nil, fmt.Errorf("%s contains several templates, specify the language: craft new <language> --from %s", source, from)
+	}
+
+	s.layers = append([]templatefs.Layer{{Name: source.String(), FS: checkoutFS}}, s.layers...)
+	s.layered = templatefs.NewLayered(s.layers...)
+	if err := registerTemplateLanguages(s.layered); err != nil {
+		return nil, "", nil, err
+	}
+	return nil, language, templateSource, nil
+}
+
+// versions returns the version of the template a project was generated from (nil if it is not available)
+// and its current version. Templates from git repositories are updated to the recorded ref, or to the ref 'to'.
+func (s *templateSource) versions(recorded *generation.Manifest, to string) (*updater.Template, updater.Template, error) {
+	template := recorded.Template
+
+	// only templates from git repositories record a ref
+	if template.Ref == "" {
+		if to != "" {
+			return nil, updater.Template{}, fmt.Errorf("the template '%s' is not from a git repository, it has no refs to update to", template.ID)
+		}
+
+		current := updater.Template{FS: s.FS(), Path: template.Path}
+		// built-in templates are versioned with craft, development builds change without a version change
+		if template.Source == "built-in" && template.Revision == constants.Version && constants.Version != "dev" {
+			return &current, current, nil
+		}
+		return nil, current, nil
+	}
+
+	cacheDir, err := gitsource.DefaultCacheDir()
+	if err != nil {
+		return nil, updater.Template{}, err
+	}
+
+	base, err := gitsource.Fetch(gitsource.Source{URL: template.Source, Ref: template.Revision}, cacheDir)
+	if err != nil {
+		return nil, updater.Template{}, err
+	}
+
+	ref := to
+	if ref == "" {
+		ref = template.Ref
+	}
+	// the ref may point to a newer commit than the cached one
+	current, err := gitsource.Refresh(gitsource.Source{URL: template.Source, Ref: ref}, cacheDir)
+	if err != nil {
+		return nil, updater.Template{}, err
+	}
+
+	baseVersion := &updater.Template{
+		FS:     os.DirFS(base.Dir),
+		Path:   template.Path,
+		Source: &common.TemplateSource{URL: template.Source, Ref: template.Ref, Commit: base.Commit},
+	}
+	currentVersion := updater.Template{
+		FS:     os.DirFS(current.Dir),
+		Path:   template.Path,
+		Source: &common.TemplateSource{URL: template.Source, Ref: ref, Commit: current.Commit},
+	}
+	return baseVersion, currentVersion, nil
+}
+
+// FS returns the layered template filesystem.
+func (s *templateSource) FS() fs.FS {
+	if s.layered == nil {
+		return s.embedded
+	}
+	return s.layered
+}
+
+// Source returns where the template in dir comes from (a template directory or "built-in").
+func (s *templateSource) Source(dir string) string {
+	if s.layered == nil {
+		return "built-in"
+	}
+	return s.layered.Source(dir)
+}
+
+// userTemplateDir returns the directory for the user's own templates, e.g. ~/.config/craft/templates.
+func userTemplateDir() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, constants.ToolName, "templates")
+}
+
+// withoutInvalidTemplates hides the templates of the user config directory whose manifest can not be loaded.
+// A broken template there must not break every command, templates from --template-dir are explicit and still fail.
+func withoutInvalidTemplates(templatesFS fs.FS, userDir string) fs.FS {
+	var invalid []string
+	err := fs.WalkDir(templatesFS, "templates", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() != manifest.FileName {
+			return nil
+		}
+		dir := path.Dir(p)
+		if _, err := manifest.Load(templatesFS, dir); err != nil {
+			fmt.Fprintf(os.Stderr, "Skipping the template %s: %v\n", filepath.Join(userDir, strings.TrimPrefix(dir, "templates/")), err)
+			invalid = append(invalid, dir)
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Skipping the templates in %s: %v\n", userDir, err)
+		return templatefs.Exclude(templatesFS, "templates")
+	}
+	return templatefs.Exclude(templatesFS, invalid...)
+}
+
+// registerTemplateLanguages allows the 'new' and 'add' operations for every language that has a template manifest,
+// so templates for new languages work without a dedicated handler.
+func registerTemplateLanguages(templatesFS fs.FS) error {
+	discovered, err := manifest.Discover(templatesFS, "templates")
+	if err != nil {
+		return fmt.Errorf("error loading the template manifests: %w", err)
+	}
+
+	for _, template := range discovered {
+		if template.Manifest.IsComponent() {
+			continue
+		}
+		registry.RegisterLanguage("new", template.Manifest.Language)
+		registry.RegisterLanguage("add", template.Manifest.Language)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWithoutInvalidTemplates(t *testing.T) {
+	templatesFS := fstest.MapFS{
+		"templates/go/template.yaml":       {Data: []byte("name: go\nlanguage: go\n")},
+		"templates/broken/template.yaml":   {Data: []byte("name: [broken\n")},
+		"templates/broken/main.go":         {Data: []byte("package main\n")},
+		"templates/nameless/template.yaml": {Data: []byte("language: go\n")},
+	}
+
+	valid := withoutInvalidTemplates(templatesFS, t.TempDir())
+
+	if _, err := fs.Stat(valid, "templates/go/template.yaml"); err != nil {
+		t.Errorf("the valid template is hidden: %v", err)
+	}
+	for _, name := range []string{"templates/broken", "templates/broken/main.go", "templates/nameless"} {
+		if _, err := fs.Stat(valid, name); err == nil {
+			t.Errorf("%s of an invalid template is not hidden", name)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"craft/internal/generation"
+	"craft/internal/updater"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// NewUpdateCmd creates a new "update" command that applies the current version of a template to a project generated from it.
+// The changes of the template are merged with a three-way merge, conflicts are written with conflict markers.
+func NewUpdateCmd(templates *templateSource) *cobra.Command {
+	var to string
+	var variables []string
+
+	cmd := &cobra.Command{
+		Use:   "update [project-directory]",
+		Short: "Apply the current version of the template to a generated project",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectDir := "."
+			if len(args) > 0 {
+				projectDir = args[0]
+			}
+			projectDir, err := filepath.Abs(projectDir)
+			if err != nil {
+				return err
+			}
+
+			recorded, err := generation.Load(projectDir)
+			if err != nil {
+				return err
+			}
+
+			templateVariables, err := parseVariables(variables)
+			if err != nil {
+				return err
+			}
+
+			base, current, err := templates.versions(recorded, to)
+			if err != nil {
+				return err
+			}
+			if base == nil {
+				fmt.Printf("The original version of the template '%s' is not available, files changed in the project and the template are not merged.\n\n", recorded.Template.ID)
+			}
+
+			u := &updater.Updater{
+				ProjectDir: projectDir,
+				Recorded:   recorded,
+				Base:       base,
+				Current:    current,
+				Variables:  templateVariables,
+			}
+
+			changes, err := u.Run()
+			updater.PrintReport(projectDir, changes)
+			if err != nil {
+				return err
+			}
+
+			if updater.HasConflicts(changes) {
+				fmt.Println("\nSome files need your attention, check them before committing the update.")
+			}
+			return nil
+		},
+
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().StringVar(&to, "to", "", "Update a template from a git repository to this tag, branch or commit instead of its recorded ref")
+	cmd.Flags().StringArrayVar(&variables, "set", nil, "Set a template variable, e.g. one the current template version added (e.g. --set Port=8080)")
+
+	return cmd
+}
@@ -3,8 +3,43 @@ package common
 import "io/fs"
 
 // NewHandler defines the interface for creating a new project.
-// It includes methods for running the handler and setting a template filesystem.
+// It includes methods for running the handler, setting a template filesystem and the options of the run.
 type NewHandler interface {
 	Run(projectName string) error
 	SetTemplatesFS(fs fs.FS)
+	SetOptions(options Options)
+}
+
+// Options holds the settings of a 'new' run that are shared by all handlers.
+type Options struct {
+	// Variables overrides the template variables declared in the manifest (--set key=value).
+	Variables map[string]string
+	// TemplateSource is set if the template was not built into craft but fetched (--from git+<url>#<ref>).
+	TemplateSource *TemplateSource
+	// DryRun prints the generation plan instead of creating the project (--dry-run).
+	DryRun bool
+	// KeepFailed keeps the staging directory of a failed generation for debugging (--keep-failed).
+	KeepFailed bool
+	// Into generates the files into this existing directory instead of a new project directory (--into).
+	Into string
+	// Conflict is the strategy for generated files that already exist in Into (--conflict).
+	Conflict string
+	// ModulePath is the module path of go projects (--module), by default it is derived from the project name.
+	ModulePath string
+	// Runtime is the container runtime (--runtime), by default the first available one is detected.
+	Runtime string
+	// NoDocker renders the skeletons of the templates instead of running their containers (--no-docker).
+	NoDocker bool
+	// Skeleton renders the skeletons of the templates in place of their containers like NoDocker, but keeps the context as it is.
+	// 'craft update' renders template versions this way, the project has the output of the containers already.
+	Skeleton bool
+	// NoCache runs the cached containers of the templates even if their output is cached (--no-cache).
+	NoCache bool
+}
+
+// TemplateSource records the repository and revision a template was fetched from.
+type TemplateSource struct {
+	URL    string `json:"url"`
+	Ref    string `json:"ref"`
+	Commit string `json:"commit"`
 }
@@ -0,0 +1,59 @@
+// Package config reads the user configuration of craft, e.g. ~/.config/craft/config.yaml.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"craft/internal/constants"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the user configuration inside the craft directory of the user's config directory.
+const FileName = "config.yaml"
+
+// Config holds the defaults of the user.
+type Config struct {
+	Go Go `yaml:"go"`
+}
+
+// Go holds the defaults of go projects.
+type Go struct {
+	// ModulePrefix is prepended to the project name to form the module path, e.g. gitlab.company.com/team.
+	ModulePrefix string `yaml:"modulePrefix"`
+}
+
+// Path returns the path of the user configuration, e.g. ~/.config/craft/config.yaml.
+func Path() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, constants.ToolName, FileName), nil
+}
+
+// Load reads the user configuration, a missing file is an empty configuration.
+func Load() (*Config, error) {
+	path, err := Path()
+	if err != nil {
+		return &Config{}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading the configuration %s: %w", path, err)
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("error parsing the configuration %s: %w", path, err)
+	}
+	return &config, nil
+}
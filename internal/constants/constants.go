@@ -1,9 +1,15 @@
 package constants
 
 const ToolName = "craft"
+
+// Version is set when building a release: go build -ldflags "-X craft/internal/constants.Version=v1.2.0"
+var Version = "dev"
+
 const (
-	TemplateFileSuffix     = ".template"
-	DotFileNotationPrefix  = "DOT"
-	DotFilePrefix          = "."
-	ProjectNamePlaceholder = "{PROJECT_NAME}"
+	TemplateFileSuffix    = ".template"
+	DotFileNotationPrefix = "DOT"
+	DotFilePrefix         = "."
 )
+
+// CraftDir is created inside generated projects and holds the information about how the project was generated.
+const CraftDir = ".craft"
@@ -0,0 +1,19 @@
+package container
+
+import (
+	"io"
+
+	"craft/internal/utils"
+)
+
+// archiveDir streams the directory as a tar archive, the build context of the daemon.
+// Errors while reading the directory fail the reader, and with it the request.
+func archiveDir(dir string) io.ReadCloser {
+	reader, writer := io.Pipe()
+
+	go func() {
+		writer.CloseWithError(utils.TarDir(dir, writer))
+	}()
+
+	return reader
+}
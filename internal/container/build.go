@@ -0,0 +1,116 @@
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// BuildOptions describe an image build, the context directory is sent to the daemon as a tar archive.
+type BuildOptions struct {
+	ContextDir string
+	// Dockerfile is relative to ContextDir.
+	Dockerfile string
+	Tag        string
+	BuildArgs  map[string]string
+	Labels     map[string]string
+}
+
+// buildMessage is one JSON object of the stream the daemon answers /build with.
+type buildMessage struct {
+	Stream      string `json:"stream"`
+	Status      string `json:"status"`
+	ID          string `json:"id"`
+	Progress    string `json:"progress"`
+	Error       string `json:"error"`
+	ErrorDetail struct {
+		Message string `json:"message"`
+	} `json:"errorDetail"`
+}
+
+// stepPattern matches the line the legacy builder prints at the start of a step: "Step 2/7 : RUN make"
+var stepPattern = regexp.MustCompile(`^Step (\d+)/(\d+) : (.*)$`)
+
+// Build builds the image and sends the steps and their output to progress.
+// A failed step is returned as *BuildError.
+func (c *Client) Build(ctx context.Context, opts BuildOptions, progress Progress) error {
+	query := url.Values{}
+	query.Set("t", opts.Tag)
+	query.Set("dockerfile", opts.Dockerfile)
+	query.Set("rm", "1")
+	query.Set("forcerm", "1")
+	if len(opts.BuildArgs) > 0 {
+		buildArgs, err := json.Marshal(opts.BuildArgs)
+		if err != nil {
+			return fmt.Errorf("error encoding the build args: %w", err)
+		}
+		query.Set("buildargs", string(buildArgs))
+	}
+	if len(opts.Labels) > 0 {
+		labels, err := json.Marshal(opts.Labels)
+		if err != nil {
+			return fmt.Errorf("error encoding the labels: %w", err)
+		}
+		query.Set("labels", string(labels))
+	}
+
+	archive := archiveDir(opts.ContextDir)
+	defer archive.Close()
+
+	resp, err := c.do(ctx, http.MethodPost, "/build", query, archive, "application/x-tar")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return readBuildStream(resp.Body, opts.Tag, progress)
+}
+
+// readBuildStream follows the steps of the build stream until it ends or reports an error.
+func readBuildStream(r io.Reader, image string, progress Progress) error {
+	decoder := json.NewDecoder(r)
+	step, total, instruction := 0, 0, ""
+
+	for {
+		var msg buildMessage
+		if err := decoder.Decode(&msg); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("error reading the build output of %s: %w", image, err)
+		}
+
+		if msg.Error != "" || msg.ErrorDetail.Message != "" {
+			message := msg.ErrorDetail.Message
+			if message == "" {
+				message = msg.Error
+			}
+			return &BuildError{Image: image, Step: step, Total: total, Instruction: instruction, Message: strings.TrimSpace(message)}
+		}
+
+		// pulls report the progress of every layer, only their final status is of interest
+		if msg.Status != "" && msg.Progress == "" {
+			progress.send(Event{Kind: EventStatus, Image: image, Message: strings.TrimSpace(msg.Status)})
+		}
+
+		for _, line := range strings.Split(msg.Stream, "\n") {
+			line = strings.TrimRight(line, "\r ")
+			if line == "" {
+				continue
+			}
+			if match := stepPattern.FindStringSubmatch(line); match != nil {
+				step, _ = strconv.Atoi(match[1])
+				total, _ = strconv.Atoi(match[2])
+				instruction = match[3]
+				progress.send(Event{Kind: EventStep, Image: image, Step: step, Total: total, Message: instruction})
+				continue
+			}
+			progress.send(Event{Kind: EventOutput, Image: image, Step: step, Total: total, Message: line})
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package container
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"craft/internal/utils"
+)
+
+// cli is the Runtime of podman without an active API socket and of nerdctl, which has none.
+// Both accept the arguments of the docker CLI.
+type cli struct {
+	name string
+}
+
+func newCLI(name string) *cli {
+	return &cli{name: name}
+}
+
+func (c *cli) Name() string {
+	return c.name
+}
+
+// User returns root for rootless podman and nerdctl, they run rootless unless craft runs as root.
+func (c *cli) User() (uid, gid int) {
+	if os.Getuid() != 0 {
+		return 0, 0
+	}
+	return os.Getuid(), os.Getgid()
+}
+
+func (c *cli) Ping(ctx context.Context) error {
+	if err := exec.CommandContext(ctx, c.name, "version").Run(); err != nil {
+		return &DaemonUnreachableError{Runtime: c.name, Err: err}
+	}
+	return nil
+}
+
+var (
+	// podman prints "STEP 2/7: RUN make"
+	podmanStepPattern = regexp.MustCompile(`^STEP (\d+)/(\d+): (.*)$`)
+	// buildkit (nerdctl) prints "#6 [builder 2/7] RUN make" and the output of the step as "#6 0.512 output"
+	buildkitStepPattern   = regexp.MustCompile(`^#\d+ \[(?:\S+ )?(\d+)/(\d+)\] (.*)$`)
+	buildkitOutputPattern = regexp.MustCompile(`^#\d+ \d+\.\d+ (.*)$`)
+)
+
+func (c *cli) Build(ctx context.Context, opts BuildOptions, progress Progress) error {
+	args := []string{"build", "-f", filepath.Join(opts.ContextDir, opts.Dockerfile), "-t", opts.Tag}
+	if c.name == Nerdctl {
+		args = append(args, "--progress=plain")
+	}
+	for _, name := range utils.SortedKeys(opts.BuildArgs) {
+		args = append(args, "--build-arg", name+"="+opts.BuildArgs[name])
+	}
+	for _, name := range utils.SortedKeys(opts.Labels) {
+		args = append(args, "--label", name+"="+opts.Labels[name])
+	}
+	args = append(args, opts.ContextDir)
+
+	step, total, instruction, lastLine := 0, 0, "", ""
+	err := c.stream(ctx, args, func(line string) {
+		match := podmanStepPattern.FindStringSubmatch(line)
+		if match == nil {
+			match = buildkitStepPattern.FindStringSubmatch(line)
+		}
+		if match != nil {
+			current, _ := strconv.Atoi(match[1])
+			// buildkit repeats the step line when it reports the step as done
+			if current != step || match[3] != instruction {
+				step, instruction = current, match[3]
+				total, _ = strconv.Atoi(match[2])
+				progress.send(Event{Kind: EventStep, Image: opts.Tag, Step: step, Total: total, Message: instruction})
+			}
+			return
+		}
+
+		if output := buildkitOutputPattern.FindStringSubmatch(line); output != nil {
+			line = output[1]
+		} else if c.name == Nerdctl && strings.HasPrefix(line, "#") {
+			// the other buildkit lines report the state of the steps
+			return
+		}
+		lastLine = line
+		progress.send(Event{Kind: EventOutput, Image: opts.Tag, Step: step, Total: total, Message: line})
+	})
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &BuildError{Image: opts.Tag, Step: step, Total: total, Instruction: instruction, Message: lastLine}
+	}
+	return err
+}
+
+func (c *cli) Run(ctx context.Context, opts RunOptions, progress Progress) error {
+	user := opts.User
+	if user == "" {
+		user = UserOf(c)
+	}
+
+	args := []string{"run", "--rm", "--user", user}
+	for _, bind := range opts.Binds {
+		args = append(args, "--volume", bind)
+	}
+	if opts.WorkingDir != "" {
+		args = append(args, "--workdir", opts.WorkingDir)
+	}
+	for _, name := range utils.SortedKeys(opts.Labels) {
+		args = append(args, "--label", name+"="+opts.Labels[name])
+	}
+	args = append(append(args, opts.Image), opts.Cmd...)
+
+	err := c.stream(ctx, args, func(line string) {
+		progress.send(Event{Kind: EventOutput, Image: opts.Image, Message: line})
+	})
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &RunError{Image: opts.Image, ExitCode: exitErr.ExitCode()}
+	}
+	return err
+}
+
+// stream runs the CLI and passes every line it prints to line, stdout and stderr combined.
+func (c *cli) stream(ctx context.Context, args []string, line func(string)) error {
+	cmd := exec.CommandContext(ctx, c.name, args...)
+	reader, writer := io.Pipe()
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+
+	if err := cmd.Start(); err != nil {
+		return &DaemonUnreachableError{Runtime: c.name, Err: err}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+		writer.Close()
+	}()
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line(strings.TrimRight(scanner.Text(), "\r"))
+	}
+	// a line longer than the buffer stops the scanner, the process must still be able to finish
+	_, _ = io.Copy(io.Discard, reader)
+
+	if err := <-done; err != nil {
+		return fmt.Errorf("%s %s: %w", c.name, args[0], err)
+	}
+	return nil
+}
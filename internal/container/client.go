@@ -0,0 +1,156 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// DefaultSocket is the unix socket of the docker daemon, used if DOCKER_HOST does not name another one.
+const DefaultSocket = "/var/run/docker.sock"
+
+// Client talks to the Docker Engine API over a unix socket, it is the Runtime of docker
+// and of podman (which serves the same API). The paths are not versioned, the daemon answers
+// with its current API version.
+type Client struct {
+	Socket   string
+	name     string
+	rootless bool
+	http     *http.Client
+}
+
+// NewClient creates a docker client for the unix socket, an empty socket uses SocketFromEnv.
+func NewClient(socket string) *Client {
+	if socket == "" {
+		socket = SocketFromEnv()
+	}
+	return newAPIClient(Docker, socket)
+}
+
+// newAPIClient creates a client for the runtime that serves the docker API on the socket.
+func newAPIClient(name, socket string) *Client {
+	dialer := &net.Dialer{}
+	return &Client{
+		Socket: socket,
+		name:   name,
+		http: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, "unix", socket)
+				},
+			},
+		},
+	}
+}
+
+// SocketFromEnv returns the socket of DOCKER_HOST (e.g. unix:///run/user/1000/docker.sock) or DefaultSocket.
+func SocketFromEnv() string {
+	if socket, ok := strings.CutPrefix(os.Getenv("DOCKER_HOST"), "unix://"); ok && socket != "" {
+		return socket
+	}
+	return DefaultSocket
+}
+
+// Name returns the runtime the socket belongs to.
+func (c *Client) Name() string {
+	return c.name
+}
+
+// User returns the current user, or root if the daemon runs rootless: its root is mapped to the current user.
+// Whether the daemon runs rootless is only known after a successful Ping.
+func (c *Client) User() (uid, gid int) {
+	if c.rootless {
+		return 0, 0
+	}
+	return os.Getuid(), os.Getgid()
+}
+
+// Ping checks that the daemon is reachable and whether it runs rootless.
+func (c *Client) Ping(ctx context.Context) error {
+	resp, err := c.do(ctx, http.MethodGet, "/_ping", nil, nil, "")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	var info struct {
+		SecurityOptions []string `json:"SecurityOptions"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, "/info", nil, nil, &info); err != nil {
+		return err
+	}
+	c.rootless = false
+	for _, option := range info.SecurityOptions {
+		if strings.Contains(option, "name=rootless") {
+			c.rootless = true
+		}
+	}
+	return nil
+}
+
+// do sends a request to the daemon. Responses with an error status are returned as *APIError,
+// requests that did not reach the daemon as *DaemonUnreachableError.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body io.Reader, contentType string) (*http.Response, error) {
+	// the host is ignored, every connection goes to the socket
+	target := "http://docker" + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("error creating the request %s %s: %w", method, path, err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &DaemonUnreachableError{Runtime: c.name, Socket: c.Socket, Err: err}
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		return nil, newAPIError(method, path, resp)
+	}
+	return resp, nil
+}
+
+// doJSON sends in as JSON (if not nil) and decodes the response into out (if not nil).
+func (c *Client) doJSON(ctx context.Context, method, path string, query url.Values, in, out any) error {
+	var body io.Reader
+	contentType := ""
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("error encoding the request %s %s: %w", method, path, err)
+		}
+		body = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+
+	resp, err := c.do(ctx, method, path, query, body, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("error decoding the response of %s %s: %w", method, path, err)
+	}
+	return nil
+}
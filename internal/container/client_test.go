@@ -0,0 +1,339 @@
+package container
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newFakeDaemon starts an httptest server with the handler on a unix socket and returns a client for it.
+func newFakeDaemon(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("listening on %s: %v", socket, err)
+	}
+
+	server := httptest.NewUnstartedServer(handler)
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+
+	return NewClient(socket)
+}
+
+// logFrame encodes a frame of the multiplexed log stream of a container.
+func logFrame(stream byte, payload string) []byte {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	return append(header, payload...)
+}
+
+func TestPing(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []string
+		rootless bool
+	}{
+		{name: "rootful", options: []string{"name=seccomp,profile=builtin"}},
+		{name: "rootless", options: []string{"name=seccomp,profile=builtin", "name=rootless"}, rootless: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/_ping", func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, "OK")
+			})
+			mux.HandleFunc("/info", func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(map[string]any{"SecurityOptions": tt.options})
+			})
+			client := newFakeDaemon(t, mux)
+
+			if err := client.Ping(context.Background()); err != nil {
+				t.Fatalf("Ping: %v", err)
+			}
+			uid, gid := client.User()
+			if tt.rootless && (uid != 0 || gid != 0) {
+				t.Errorf("User() = %d:%d, want 0:0 for a rootless daemon", uid, gid)
+			}
+			if !tt.rootless && (uid != os.Getuid() || gid != os.Getgid()) {
+				t.Errorf("User() = %d:%d, want the current user", uid, gid)
+			}
+		})
+	}
+}
+
+func TestPingDaemonUnreachable(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "missing.sock")
+	client := NewClient(socket)
+
+	err := client.Ping(context.Background())
+	var unreachable *DaemonUnreachableError
+	if !errors.As(err, &unreachable) {
+		t.Fatalf("Ping() = %v, want a *DaemonUnreachableError", err)
+	}
+	if unreachable.Socket != socket || unreachable.Runtime != Docker {
+		t.Errorf("got %s at %s, want docker at %s", unreachable.Runtime, unreachable.Socket, socket)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	var query map[string]string
+	var contextFiles []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/build", func(w http.ResponseWriter, r *http.Request) {
+		query = map[string]string{}
+		for name := range r.URL.Query() {
+			query[name] = r.URL.Query().Get(name)
+		}
+		body, _ := io.ReadAll(r.Body)
+		for _, name := range []string{"Dockerfile", "setup.sh"} {
+			if strings.Contains(string(body), name) {
+				contextFiles = append(contextFiles, name)
+			}
+		}
+
+		encoder := json.NewEncoder(w)
+		encoder.Encode(map[string]string{"stream": "Step 1/2 : FROM alpine\n"})
+		encoder.Encode(map[string]string{"status": "Pulling fs layer", "progress": "[=>  ]"})
+		encoder.Encode(map[string]string{"status": "Downloaded newer image for alpine"})
+		encoder.Encode(map[string]string{"stream": "Step 2/2 : RUN ./setup.sh\n"})
+		encoder.Encode(map[string]string{"stream": "setting up\n"})
+	})
+	client := newFakeDaemon(t, mux)
+
+	contextDir := t.TempDir()
+	os.WriteFile(filepath.Join(contextDir, "Dockerfile"), []byte("FROM alpine\n"), 0644)
+	os.WriteFile(filepath.Join(contextDir, "setup.sh"), []byte("#!/bin/sh\n"), 0755)
+
+	var events []Event
+	opts := BuildOptions{
+		ContextDir: contextDir,
+		Dockerfile: "Dockerfile",
+		Tag:        "generator:latest",
+		BuildArgs:  map[string]string{"UID": "1000"},
+		Labels:     ImageLabels("java-maven-default"),
+	}
+	if err := client.Build(context.Background(), opts, func(e Event) { events = append(events, e) }); err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	if query["t"] != "generator:latest" || query["dockerfile"] != "Dockerfile" {
+		t.Errorf("unexpected query %v", query)
+	}
+	if query["buildargs"] != `{"UID":"1000"}` {
+		t.Errorf("buildargs = %s", query["buildargs"])
+	}
+	if !strings.Contains(query["labels"], `"craft.managed":"true"`) {
+		t.Errorf("labels = %s", query["labels"])
+	}
+	if len(contextFiles) != 2 {
+		t.Errorf("the build context contained %v, want the Dockerfile and setup.sh", contextFiles)
+	}
+
+	want := []Event{
+		{Kind: EventStep, Image: "generator:latest", Step: 1, Total: 2, Message: "FROM alpine"},
+		{Kind: EventStatus, Image: "generator:latest", Message: "Downloaded newer image for alpine"},
+		{Kind: EventStep, Image: "generator:latest", Step: 2, Total: 2, Message: "RUN ./setup.sh"},
+		{Kind: EventOutput, Image: "generator:latest", Step: 2, Total: 2, Message: "setting up"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got events %+v, want %+v", events, want)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, events[i], want[i])
+		}
+	}
+}
+
+func TestBuildFailsAtStep(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/build", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		encoder := json.NewEncoder(w)
+		encoder.Encode(map[string]string{"stream": "Step 1/3 : FROM maven\n"})
+		encoder.Encode(map[string]string{"stream": "Step 2/3 : RUN mvn archetype:generate\n"})
+		encoder.Encode(map[string]any{
+			"error":       "The command returned a non-zero code: 1",
+			"errorDetail": map[string]any{"code": 1, "message": "The command '/bin/sh -c mvn archetype:generate' returned a non-zero code: 1"},
+		})
+	})
+	client := newFakeDaemon(t, mux)
+
+	err := client.Build(context.Background(), BuildOptions{ContextDir: t.TempDir(), Dockerfile: "Dockerfile", Tag: "generator:latest"}, nil)
+
+	var buildErr *BuildError
+	if !errors.As(err, &buildErr) {
+		t.Fatalf("Build() = %v, want a *BuildError", err)
+	}
+	if buildErr.Step != 2 || buildErr.Total != 3 || buildErr.Instruction != "RUN mvn archetype:generate" {
+		t.Errorf("got step %d/%d (%s), want 2/3 (RUN mvn archetype:generate)", buildErr.Step, buildErr.Total, buildErr.Instruction)
+	}
+	if !strings.Contains(err.Error(), "failed at step 2/3") {
+		t.Errorf("unexpected message: %v", err)
+	}
+}
+
+func TestBuildAPIError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/build", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, `{"message":"no space left on device"}`)
+	})
+	client := newFakeDaemon(t, mux)
+
+	err := client.Build(context.Background(), BuildOptions{ContextDir: t.TempDir(), Dockerfile: "Dockerfile", Tag: "x"}, nil)
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Build() = %v, want an *APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusInternalServerError || apiErr.Message != "no space left on device" {
+		t.Errorf("got %d %q", apiErr.StatusCode, apiErr.Message)
+	}
+}
+
+// containerDaemon serves create, start, logs, wait and delete of a single container.
+type containerDaemon struct {
+	created  createRequest
+	started  bool
+	removed  bool
+	exitCode int
+	logs     []byte
+}
+
+func (d *containerDaemon) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/create", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&d.created)
+		json.NewEncoder(w).Encode(map[string]string{"Id": "c1"})
+	})
+	mux.HandleFunc("/containers/c1/start", func(w http.ResponseWriter, r *http.Request) {
+		d.started = true
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/containers/c1/logs", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(d.logs)
+	})
+	mux.HandleFunc("/containers/c1/wait", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]int{"StatusCode": d.exitCode})
+	})
+	mux.HandleFunc("/containers/c1", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			d.removed = true
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+func TestRun(t *testing.T) {
+	var logs []byte
+	logs = append(logs, logFrame(1, "copying fi")...)
+	logs = append(logs, logFrame(2, "warning: slow disk\n")...)
+	logs = append(logs, logFrame(1, "les\ndone")...)
+	daemon := &containerDaemon{logs: logs}
+	client := newFakeDaemon(t, daemon.handler())
+
+	var lines []string
+	opts := RunOptions{
+		Image: "generator:latest",
+		Cmd:   []string{"cp", "-r", "/build-space/.", "/workspace"},
+		User:  "1000:1000",
+		Binds: []string{"/tmp/project:/workspace"},
+	}
+	err := client.Run(context.Background(), opts, func(e Event) {
+		if e.Kind == EventOutput {
+			lines = append(lines, e.Message)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if daemon.created.Image != "generator:latest" || daemon.created.User != "1000:1000" {
+		t.Errorf("created %+v", daemon.created)
+	}
+	if len(daemon.created.HostConfig.Binds) != 1 || daemon.created.HostConfig.Binds[0] != "/tmp/project:/workspace" {
+		t.Errorf("binds = %v", daemon.created.HostConfig.Binds)
+	}
+	if !daemon.started || !daemon.removed {
+		t.Errorf("started = %v, removed = %v, want both", daemon.started, daemon.removed)
+	}
+
+	// a line split across frames of the same stream is put back together
+	want := []string{"warning: slow disk", "copying files", "done"}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+}
+
+func TestRunExitCode(t *testing.T) {
+	daemon := &containerDaemon{exitCode: 3}
+	client := newFakeDaemon(t, daemon.handler())
+
+	err := client.Run(context.Background(), RunOptions{Image: "generator:latest", User: "0:0"}, nil)
+
+	var runErr *RunError
+	if !errors.As(err, &runErr) {
+		t.Fatalf("Run() = %v, want a *RunError", err)
+	}
+	if runErr.ExitCode != 3 {
+		t.Errorf("ExitCode = %d, want 3", runErr.ExitCode)
+	}
+	if !daemon.removed {
+		t.Error("the failed container was not removed")
+	}
+}
+
+func TestDemuxLogs(t *testing.T) {
+	tests := []struct {
+		name   string
+		frames [][]byte
+		want   []string
+	}{
+		{name: "empty", want: nil},
+		{name: "one line per frame", frames: [][]byte{logFrame(1, "a\n"), logFrame(2, "b\n")}, want: []string{"a", "b"}},
+		{name: "several lines in a frame", frames: [][]byte{logFrame(1, "a\r\nb\n")}, want: []string{"a", "b"}},
+		{name: "unterminated last line", frames: [][]byte{logFrame(1, "a\nb")}, want: []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stream []byte
+			for _, frame := range tt.frames {
+				stream = append(stream, frame...)
+			}
+
+			var got []string
+			if err := demuxLogs(strings.NewReader(string(stream)), func(line string) { got = append(got, line) }); err != nil {
+				t.Fatalf("demuxLogs: %v", err)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDemuxLogsTruncated(t *testing.T) {
+	frame := logFrame(1, "complete line\n")
+	if err := demuxLogs(strings.NewReader(string(frame[:len(frame)-3])), func(string) {}); err == nil {
+		t.Error("a truncated frame did not fail")
+	}
+}
@@ -0,0 +1,94 @@
+package container
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DaemonUnreachableError is returned if the daemon of a runtime does not answer on its socket,
+// or if the CLI of a runtime without socket can not be used.
+type DaemonUnreachableError struct {
+	Runtime string
+	// Socket is empty for runtimes that are used through their CLI.
+	Socket string
+	Err    error
+}
+
+func (e *DaemonUnreachableError) Error() string {
+	if e.Socket == "" {
+		return fmt.Sprintf("%s can not be used, is it installed? (%v)", e.Runtime, e.Err)
+	}
+	return fmt.Sprintf("the %s daemon is not reachable at %s, is it running? (%v)", e.Runtime, e.Socket, e.Err)
+}
+
+func (e *DaemonUnreachableError) Unwrap() error {
+	return e.Err
+}
+
+// IsUnavailable reports whether the error of Detect or Ping means that no container runtime can be used at all.
+func IsUnavailable(err error) bool {
+	var unreachable *DaemonUnreachableError
+	return errors.Is(err, ErrNoRuntime) || errors.As(err, &unreachable)
+}
+
+// APIError is an error status the daemon answered a request with.
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("docker API %s %s failed with status %d: %s", e.Method, e.Path, e.StatusCode, e.Message)
+}
+
+// newAPIError reads the message of the daemon's error response: {"message": "..."}
+func newAPIError(method, path string, resp *http.Response) *APIError {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	var body struct {
+		Message string `json:"message"`
+	}
+	message := strings.TrimSpace(string(data))
+	if err := json.Unmarshal(data, &body); err == nil && body.Message != "" {
+		message = body.Message
+	}
+
+	return &APIError{Method: method, Path: path, StatusCode: resp.StatusCode, Message: message}
+}
+
+// BuildError is returned if a step of an image build failed.
+// Step and Total are 0 if the build failed before the first step, e.g. for an invalid Dockerfile.
+type BuildError struct {
+	Image       string
+	Step        int
+	Total       int
+	Instruction string
+	Message     string
+}
+
+func (e *BuildError) Error() string {
+	if e.Step == 0 {
+		return fmt.Sprintf("building the image %s failed: %s", e.Image, e.Message)
+	}
+	return fmt.Sprintf("building the image %s failed at step %d/%d (%s): %s", e.Image, e.Step, e.Total, e.Instruction, e.Message)
+}
+
+// RunError is returned if a container exited with a status other than 0.
+type RunError struct {
+	Image    string
+	ExitCode int
+	Message  string
+}
+
+func (e *RunError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("the container of the image %s exited with status %d: %s", e.Image, e.ExitCode, e.Message)
+	}
+	return fmt.Sprintf("the container of the image %s exited with status %d", e.Image, e.ExitCode)
+}
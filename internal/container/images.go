@@ -0,0 +1,181 @@
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Labels of the images craft builds, they tell them apart from every other image on the host.
+const (
+	// LabelManaged is "true" on every image craft built.
+	LabelManaged = "craft.managed"
+	// LabelTemplate is the name of the template whose container the image was built for.
+	LabelTemplate = "craft.template"
+)
+
+// ImageLabels returns the labels of an image built for the template.
+func ImageLabels(template string) map[string]string {
+	return map[string]string{LabelManaged: "true", LabelTemplate: template}
+}
+
+// Image is an image craft built, see ImageLabels.
+type Image struct {
+	ID string
+	// Tags are the names of the image, e.g. maven-project-generator:latest.
+	Tags     []string
+	Template string
+	// Size is in bytes.
+	Size    int64
+	Created time.Time
+}
+
+// Name returns the first tag of the image, or its short ID if it has none (e.g. after the tag moved to a newer build).
+func (i Image) Name() string {
+	if len(i.Tags) > 0 {
+		return i.Tags[0]
+	}
+	return shortID(i.ID)
+}
+
+func shortID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// managedFilter selects the images with LabelManaged.
+var managedFilter = LabelManaged + "=true"
+
+// imageSummary is an entry of the answer to /images/json.
+type imageSummary struct {
+	ID       string            `json:"Id"`
+	RepoTags []string          `json:"RepoTags"`
+	Size     int64             `json:"Size"`
+	Created  int64             `json:"Created"`
+	Labels   map[string]string `json:"Labels"`
+}
+
+// Images returns the images craft built.
+func (c *Client) Images(ctx context.Context) ([]Image, error) {
+	filters, err := json.Marshal(map[string][]string{"label": {managedFilter}})
+	if err != nil {
+		return nil, fmt.Errorf("error encoding the image filter: %w", err)
+	}
+
+	var summaries []imageSummary
+	if err := c.doJSON(ctx, http.MethodGet, "/images/json", url.Values{"filters": {string(filters)}}, nil, &summaries); err != nil {
+		return nil, err
+	}
+
+	images := make([]Image, 0, len(summaries))
+	for _, summary := range summaries {
+		images = append(images, Image{
+			ID:       summary.ID,
+			Tags:     validTags(summary.RepoTags),
+			Template: summary.Labels[LabelTemplate],
+			Size:     summary.Size,
+			Created:  time.Unix(summary.Created, 0),
+		})
+	}
+	return images, nil
+}
+
+// RemoveImage removes the image with the tag or ID. An image a container still uses is not removed.
+func (c *Client) RemoveImage(ctx context.Context, image string) error {
+	resp, err := c.do(ctx, http.MethodDelete, "/images/"+image, nil, nil, "")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// imageInspect is the part of the docker compatible output of 'image inspect' craft needs.
+type imageInspect struct {
+	ID       string    `json:"Id"`
+	RepoTags []string  `json:"RepoTags"`
+	Size     int64     `json:"Size"`
+	Created  time.Time `json:"Created"`
+	Config   struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+}
+
+func (c *cli) Images(ctx context.Context) ([]Image, error) {
+	out, err := c.output(ctx, "images", "--quiet", "--no-trunc", "--filter", "label="+managedFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	// an image with several tags is listed once per tag
+	var ids []string
+	seen := map[string]bool{}
+	for _, id := range strings.Fields(out) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	out, err = c.output(ctx, append([]string{"image", "inspect"}, ids...)...)
+	if err != nil {
+		return nil, err
+	}
+	var inspected []imageInspect
+	if err := json.Unmarshal([]byte(out), &inspected); err != nil {
+		return nil, fmt.Errorf("error decoding the output of %s image inspect: %w", c.name, err)
+	}
+
+	images := make([]Image, 0, len(inspected))
+	for _, image := range inspected {
+		images = append(images, Image{
+			ID:       image.ID,
+			Tags:     validTags(image.RepoTags),
+			Template: image.Config.Labels[LabelTemplate],
+			Size:     image.Size,
+			Created:  image.Created,
+		})
+	}
+	return images, nil
+}
+
+func (c *cli) RemoveImage(ctx context.Context, image string) error {
+	_, err := c.output(ctx, "rmi", image)
+	return err
+}
+
+// output runs the CLI and returns what it printed to stdout, a failure is returned with what it printed to stderr.
+func (c *cli) output(ctx context.Context, args ...string) (string, error) {
+	out, err := exec.CommandContext(ctx, c.name, args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("%s %s failed: %s", c.name, args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", &DaemonUnreachableError{Runtime: c.name, Err: err}
+	}
+	return string(out), nil
+}
+
+// validTags drops the placeholder tag of untagged images.
+func validTags(tags []string) []string {
+	var valid []string
+	for _, tag := range tags {
+		if tag != "<none>:<none>" {
+			valid = append(valid, tag)
+		}
+	}
+	return valid
+}
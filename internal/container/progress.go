@@ -0,0 +1,55 @@
+package container
+
+import (
+	"fmt"
+	"io"
+)
+
+// Kinds of progress events.
+const (
+	// EventStep is sent when a build step starts.
+	EventStep = "step"
+	// EventStatus reports what the daemon does, e.g. pulling the base image.
+	EventStatus = "status"
+	// EventOutput is a line printed by a build step or by a container.
+	EventOutput = "output"
+)
+
+// Event is the progress of a build or a container run.
+type Event struct {
+	Kind  string
+	Image string
+	// Step and Total are set for build steps and the output of build steps.
+	Step  int
+	Total int
+	// Message is the instruction of a step, the status or the line of output.
+	Message string
+}
+
+// Progress receives the events of a build or run, it may be nil.
+type Progress func(Event)
+
+func (p Progress) send(event Event) {
+	if p != nil {
+		p(event)
+	}
+}
+
+// PrintProgress returns a Progress that prints the build steps and the output below them to w.
+func PrintProgress(w io.Writer) Progress {
+	lastStatus := ""
+	return func(event Event) {
+		switch event.Kind {
+		case EventStep:
+			fmt.Fprintf(w, "[%s] step %d/%d: %s\n", event.Image, event.Step, event.Total, event.Message)
+		case EventStatus:
+			// the same status is sent for every layer of a pulled image
+			if event.Message != lastStatus {
+				fmt.Fprintf(w, "[%s] %s\n", event.Image, event.Message)
+				lastStatus = event.Message
+			}
+		case EventOutput:
+			fmt.Fprintf(w, "  %s\n", event.Message)
+		}
+	}
+}
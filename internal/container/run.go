@@ -0,0 +1,148 @@
+package container
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RunOptions describe a container that is created from Image, run until it exits and removed again.
+type RunOptions struct {
+	Image string
+	Cmd   []string
+	// User is "uid:gid", the files the container writes to the binds belong to it. Empty runs as the User of the runtime.
+	User string
+	// Binds mount host directories into the container: "/host/dir:/container/dir"
+	Binds      []string
+	WorkingDir string
+	Labels     map[string]string
+}
+
+// UserOf returns the "uid:gid" the containers of the runtime run as.
+func UserOf(runtime Runtime) string {
+	uid, gid := runtime.User()
+	return fmt.Sprintf("%d:%d", uid, gid)
+}
+
+type createRequest struct {
+	Image      string            `json:"Image"`
+	Cmd        []string          `json:"Cmd,omitempty"`
+	User       string            `json:"User,omitempty"`
+	WorkingDir string            `json:"WorkingDir,omitempty"`
+	Labels     map[string]string `json:"Labels,omitempty"`
+	HostConfig struct {
+		Binds []string `json:"Binds,omitempty"`
+	} `json:"HostConfig"`
+}
+
+type createResponse struct {
+	ID string `json:"Id"`
+}
+
+type waitResponse struct {
+	StatusCode int `json:"StatusCode"`
+	Error      *struct {
+		Message string `json:"Message"`
+	} `json:"Error"`
+}
+
+// Run creates a container, streams its output to progress and waits until it exits.
+// The container is removed afterwards, an exit status other than 0 is returned as *RunError.
+func (c *Client) Run(ctx context.Context, opts RunOptions, progress Progress) error {
+	create := createRequest{Image: opts.Image, Cmd: opts.Cmd, User: opts.User, WorkingDir: opts.WorkingDir, Labels: opts.Labels}
+	if create.User == "" {
+		create.User = UserOf(c)
+	}
+	create.HostConfig.Binds = opts.Binds
+
+	var created createResponse
+	if err := c.doJSON(ctx, http.MethodPost, "/containers/create", nil, create, &created); err != nil {
+		return err
+	}
+	defer c.remove(created.ID)
+
+	containerPath := "/containers/" + created.ID
+	if err := c.doJSON(ctx, http.MethodPost, containerPath+"/start", nil, nil, nil); err != nil {
+		return err
+	}
+
+	// the logs of a started container contain everything it printed since it was started
+	query := url.Values{"follow": {"1"}, "stdout": {"1"}, "stderr": {"1"}}
+	resp, err := c.do(ctx, http.MethodGet, containerPath+"/logs", query, nil, "")
+	if err != nil {
+		return err
+	}
+	err = demuxLogs(resp.Body, func(line string) {
+		progress.send(Event{Kind: EventOutput, Image: opts.Image, Message: line})
+	})
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("error reading the output of the container of %s: %w", opts.Image, err)
+	}
+
+	var waited waitResponse
+	if err := c.doJSON(ctx, http.MethodPost, containerPath+"/wait", nil, nil, &waited); err != nil {
+		return err
+	}
+	if waited.StatusCode != 0 || waited.Error != nil {
+		runErr := &RunError{Image: opts.Image, ExitCode: waited.StatusCode}
+		if waited.Error != nil {
+			runErr.Message = waited.Error.Message
+		}
+		return runErr
+	}
+	return nil
+}
+
+// remove deletes the container, also if the run was cancelled.
+func (c *Client) remove(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	resp, err := c.do(ctx, http.MethodDelete, "/containers/"+id, url.Values{"force": {"1"}}, nil, "")
+	if err == nil {
+		resp.Body.Close()
+	}
+}
+
+// demuxLogs splits the multiplexed log stream of a container without a tty into lines.
+// Every frame starts with an 8 byte header: the stream (1 stdout, 2 stderr), 3 zero bytes and the big endian size.
+func demuxLogs(r io.Reader, line func(string)) error {
+	reader := bufio.NewReader(r)
+	header := make([]byte, 8)
+	pending := map[byte]string{}
+
+	for {
+		if _, err := io.ReadFull(reader, header); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		payload := make([]byte, binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return err
+		}
+
+		// a frame can end inside a line, the rest of it comes with the next frame of the same stream
+		text := pending[header[0]] + string(payload)
+		lines := strings.Split(text, "\n")
+		for _, l := range lines[:len(lines)-1] {
+			line(strings.TrimRight(l, "\r"))
+		}
+		pending[header[0]] = lines[len(lines)-1]
+	}
+
+	for _, stream := range []byte{1, 2} {
+		if rest := pending[stream]; rest != "" {
+			line(rest)
+		}
+	}
+	return nil
+}
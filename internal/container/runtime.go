@@ -0,0 +1,125 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Names of the supported container runtimes, they are also the commands of their CLIs.
+const (
+	Docker  = "docker"
+	Podman  = "podman"
+	Nerdctl = "nerdctl"
+)
+
+// Runtimes lists the supported container runtimes in the order they are detected.
+var Runtimes = []string{Docker, Podman, Nerdctl}
+
+// ErrNoRuntime is returned by Detect if none of the Runtimes is available.
+var ErrNoRuntime = errors.New("no container runtime found")
+
+// Runtime builds and runs the generator images of the templates.
+type Runtime interface {
+	// Name is one of Runtimes.
+	Name() string
+	// User returns the uid and gid inside a container whose files belong to the current user on the host.
+	// That is the current user itself, or root for rootless runtimes which map their root to the current user.
+	User() (uid, gid int)
+	// Ping checks that the runtime can be used, it has to succeed before Build and Run.
+	Ping(ctx context.Context) error
+	Build(ctx context.Context, opts BuildOptions, progress Progress) error
+	Run(ctx context.Context, opts RunOptions, progress Progress) error
+	// Images returns the images craft built (see ImageLabels), RemoveImage removes one of them by tag or ID.
+	Images(ctx context.Context) ([]Image, error)
+	RemoveImage(ctx context.Context, image string) error
+}
+
+// Detect returns the runtime with the given name, or the first available one of Runtimes if name is empty.
+// Docker and podman are used through their API socket, podman without an active socket and nerdctl through their CLI.
+func Detect(ctx context.Context, name string) (Runtime, error) {
+	switch name {
+	case Docker:
+		return NewClient(""), nil
+	case Podman:
+		if client := findAPI(ctx, Podman, podmanSockets()); client != nil {
+			return client, nil
+		}
+		return newCLI(Podman), nil
+	case Nerdctl:
+		return newCLI(Nerdctl), nil
+	case "":
+	default:
+		return nil, fmt.Errorf("unknown container runtime '%s'. Supported runtimes are: %s", name, strings.Join(Runtimes, ", "))
+	}
+
+	if client := findAPI(ctx, Docker, []string{SocketFromEnv()}); client != nil {
+		return client, nil
+	}
+	if client := findAPI(ctx, Podman, podmanSockets()); client != nil {
+		return client, nil
+	}
+	for _, cli := range []string{Podman, Nerdctl} {
+		if _, err := exec.LookPath(cli); err == nil {
+			return newCLI(cli), nil
+		}
+	}
+
+	if InContainer() {
+		return nil, fmt.Errorf("%w: craft runs inside a container, mount the socket of the runtime on the host into it (e.g. -v /var/run/docker.sock:/var/run/docker.sock)", ErrNoRuntime)
+	}
+	return nil, fmt.Errorf("%w, install one of %s or choose one with --runtime", ErrNoRuntime, strings.Join(Runtimes, ", "))
+}
+
+// findAPI returns a client for the first socket that answers, or nil.
+func findAPI(ctx context.Context, name string, sockets []string) *Client {
+	for _, socket := range sockets {
+		if _, err := os.Stat(socket); err != nil {
+			continue
+		}
+		client := newAPIClient(name, socket)
+		if client.Ping(ctx) == nil {
+			return client
+		}
+	}
+	return nil
+}
+
+// podmanSockets returns the API sockets of rootless and rootful podman (podman system service).
+func podmanSockets() []string {
+	var sockets []string
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		sockets = append(sockets, filepath.Join(runtimeDir, "podman", "podman.sock"))
+	}
+	return append(sockets, "/run/podman/podman.sock")
+}
+
+// InContainer reports whether craft itself runs inside a container (docker, podman or containerd).
+// The bind mounts of the runtime are resolved on the host then, not inside this container.
+func InContainer() bool {
+	for _, marker := range []string{"/.dockerenv", "/run/.containerenv"} {
+		if _, err := os.Stat(marker); err == nil {
+			return true
+		}
+	}
+
+	file, err := os.Open("/proc/1/cgroup")
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	buf := make([]byte, 4096)
+	n, _ := file.Read(buf)
+	cgroup := string(buf[:n])
+	for _, name := range []string{"docker", "libpod", "containerd", "kubepods"} {
+		if strings.Contains(cgroup, name) {
+			return true
+		}
+	}
+	return false
+}
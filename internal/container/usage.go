@@ -0,0 +1,85 @@
+package container
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"craft/internal/constants"
+)
+
+// Usage records when craft last ran its images, the runtimes only know when an image was built.
+// It is stored as JSON, the tag of every image mapped to its last use.
+type Usage struct {
+	path     string
+	LastUsed map[string]time.Time
+}
+
+// DefaultUsageFile returns the file the usage of the images is stored in, e.g. ~/.cache/craft/images.json.
+func DefaultUsageFile() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine the cache directory: %w", err)
+	}
+	return filepath.Join(cacheDir, constants.ToolName, "images.json"), nil
+}
+
+// LoadUsage reads the usage stored in path, a missing file is an empty usage.
+func LoadUsage(path string) (*Usage, error) {
+	usage := &Usage{path: path, LastUsed: map[string]time.Time{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return usage, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading the image usage %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &usage.LastUsed); err != nil {
+		return nil, fmt.Errorf("error parsing the image usage %s: %w", path, err)
+	}
+	return usage, nil
+}
+
+// LastUse returns when the image was last run, or when it was built if craft never ran it.
+func (u *Usage) LastUse(image Image) time.Time {
+	last := image.Created
+	for _, tag := range image.Tags {
+		if used, ok := u.LastUsed[tag]; ok && used.After(last) {
+			last = used
+		}
+	}
+	return last
+}
+
+// Save writes the usage back to its file.
+func (u *Usage) Save() error {
+	data, err := json.MarshalIndent(u.LastUsed, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding the image usage: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(u.path), 0755); err != nil {
+		return fmt.Errorf("could not create the cache directory: %w", err)
+	}
+	if err := os.WriteFile(u.path, data, 0644); err != nil {
+		return fmt.Errorf("error writing the image usage %s: %w", u.path, err)
+	}
+	return nil
+}
+
+// RecordUse stores now as the last use of the image in the DefaultUsageFile.
+func RecordUse(image string) error {
+	path, err := DefaultUsageFile()
+	if err != nil {
+		return err
+	}
+	usage, err := LoadUsage(path)
+	if err != nil {
+		return err
+	}
+	usage.LastUsed[image] = time.Now()
+	return usage.Save()
+}
@@ -0,0 +1,176 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Kinds of diff lines.
+const (
+	Equal  = ' '
+	Delete = '-'
+	Insert = '+'
+)
+
+// Line is a line of a line based diff.
+type Line struct {
+	Kind byte
+	Text string
+}
+
+// contextLines is the number of unchanged lines shown around a change in a unified diff.
+const contextLines = 3
+
+// SplitLines splits text into lines, a trailing newline does not start an empty last line.
+func SplitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// lcsTable returns the table of the lengths of the longest common subsequences:
+// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+func lcsTable(a, b []string) [][]int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	return lcs
+}
+
+// Lines compares a and b line by line, based on their longest common subsequence.
+func Lines(a, b []string) []Line {
+	lcs := lcsTable(a, b)
+
+	var lines []Line
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, Line{Kind: Equal, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, Line{Kind: Delete, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, Line{Kind: Insert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, Line{Kind: Delete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, Line{Kind: Insert, Text: b[j]})
+	}
+	return lines
+}
+
+// match returns for every line of a the index of the line of b it is matched with in their
+// longest common subsequence, or -1 if the line is not part of it.
+func match(a, b []string) []int {
+	lcs := lcsTable(a, b)
+
+	matches := make([]int, len(a))
+	for i := range matches {
+		matches[i] = -1
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			matches[i] = j
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return matches
+}
+
+// Unified returns the unified diff of the texts a and b, or an empty string if they are equal.
+func Unified(nameA, nameB, a, b string) string {
+	lines := Lines(SplitLines(a), SplitLines(b))
+
+	var sb strings.Builder
+	lineA, lineB := 1, 1
+	for start := 0; start < len(lines); {
+		if lines[start].Kind == Equal {
+			start++
+			lineA++
+			lineB++
+			continue
+		}
+
+		// a hunk starts with the context before the first change and ends
+		// once more than two times the context of unchanged lines follow
+		from := max(start-contextLines, 0)
+		end := start
+		for unchanged := 0; end < len(lines) && unchanged <= 2*contextLines; end++ {
+			if lines[end].Kind == Equal {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+		to := end
+		for to > start && lines[to-1].Kind == Equal {
+			to--
+		}
+		to = min(to+contextLines, len(lines))
+
+		hunkA, hunkB := lineA-(start-from), lineB-(start-from)
+		var countA, countB int
+		var body strings.Builder
+		for _, line := range lines[from:to] {
+			if line.Kind != Insert {
+				countA++
+			}
+			if line.Kind != Delete {
+				countB++
+			}
+			body.WriteByte(line.Kind)
+			body.WriteString(line.Text)
+			body.WriteByte('\n')
+		}
+
+		// an empty range refers to the line before it
+		if countA == 0 {
+			hunkA--
+		}
+		if countB == 0 {
+			hunkB--
+		}
+
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", nameA, nameB)
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", hunkA, countA, hunkB, countB)
+		sb.WriteString(body.String())
+
+		for _, line := range lines[start:to] {
+			if line.Kind != Insert {
+				lineA++
+			}
+			if line.Kind != Delete {
+				lineB++
+			}
+		}
+		start = to
+	}
+	return sb.String()
+}
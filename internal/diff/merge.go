@@ -0,0 +1,64 @@
+package diff
+
+import (
+	"slices"
+	"strings"
+)
+
+// Conflict markers of a three-way merge.
+const (
+	markerOurs   = "<<<<<<< "
+	markerSep    = "======="
+	markerTheirs = ">>>>>>> "
+)
+
+// Merge merges the changes from base to ours and from base to theirs line by line (diff3).
+// Changes that overlap and differ are written between conflict markers labelled with oursName and theirsName.
+// It returns the merged text and the number of conflicts.
+func Merge(base, ours, theirs, oursName, theirsName string) (string, int) {
+	baseLines, oursLines, theirsLines := SplitLines(base), SplitLines(ours), SplitLines(theirs)
+	matchOurs, matchTheirs := match(baseLines, oursLines), match(baseLines, theirsLines)
+
+	var merged []string
+	conflicts := 0
+	i, j, k := 0, 0, 0
+	for i < len(baseLines) || j < len(oursLines) || k < len(theirsLines) {
+		// unchanged line in both versions
+		if i < len(baseLines) && matchOurs[i] == j && matchTheirs[i] == k {
+			merged = append(merged, baseLines[i])
+			i, j, k = i+1, j+1, k+1
+			continue
+		}
+
+		// the changed chunk ends at the next base line that is unchanged in both versions
+		next := i
+		for next < len(baseLines) && (matchOurs[next] == -1 || matchTheirs[next] == -1) {
+			next++
+		}
+		nextOurs, nextTheirs := len(oursLines), len(theirsLines)
+		if next < len(baseLines) {
+			nextOurs, nextTheirs = matchOurs[next], matchTheirs[next]
+		}
+
+		baseChunk, oursChunk, theirsChunk := baseLines[i:next], oursLines[j:nextOurs], theirsLines[k:nextTheirs]
+		switch {
+		case slices.Equal(oursChunk, baseChunk):
+			merged = append(merged, theirsChunk...)
+		case slices.Equal(theirsChunk, baseChunk), slices.Equal(oursChunk, theirsChunk):
+			merged = append(merged, oursChunk...)
+		default:
+			conflicts++
+			merged = append(merged, markerOurs+oursName)
+			merged = append(merged, oursChunk...)
+			merged = append(merged, markerSep)
+			merged = append(merged, theirsChunk...)
+			merged = append(merged, markerTheirs+theirsName)
+		}
+		i, j, k = next, nextOurs, nextTheirs
+	}
+
+	if len(merged) == 0 {
+		return "", conflicts
+	}
+	return strings.Join(merged, "\n") + "\n", conflicts
+}
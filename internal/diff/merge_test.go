@@ -0,0 +1,130 @@
+package diff
+
+import "testing"
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name          string
+		base          string
+		ours          string
+		theirs        string
+		want          string
+		wantConflicts int
+	}{
+		{
+			name:   "unchanged",
+			base:   "a\nb\nc\n",
+			ours:   "a\nb\nc\n",
+			theirs: "a\nb\nc\n",
+			want:   "a\nb\nc\n",
+		},
+		{
+			name:   "clean merge of separate changes",
+			base:   "a\nb\nc\nd\ne\n",
+			ours:   "a\nB\nc\nd\ne\n",
+			theirs: "a\nb\nc\nD\ne\n",
+			want:   "a\nB\nc\nD\ne\n",
+		},
+		{
+			name:   "only ours changed",
+			base:   "a\nb\nc\n",
+			ours:   "a\nb\nuser line\nc\n",
+			theirs: "a\nb\nc\n",
+			want:   "a\nb\nuser line\nc\n",
+		},
+		{
+			name:   "only theirs changed",
+			base:   "a\nb\nc\n",
+			ours:   "a\nb\nc\n",
+			theirs: "a\nc\ntemplate line\n",
+			want:   "a\nc\ntemplate line\n",
+		},
+		{
+			name:   "the same change in both",
+			base:   "a\nb\nc\n",
+			ours:   "a\nB\nc\n",
+			theirs: "a\nB\nc\n",
+			want:   "a\nB\nc\n",
+		},
+		{
+			name:          "conflicting change",
+			base:          "a\nb\nc\n",
+			ours:          "a\nuser\nc\n",
+			theirs:        "a\ntemplate\nc\n",
+			want:          "a\n<<<<<<< project\nuser\n=======\ntemplate\n>>>>>>> template\nc\n",
+			wantConflicts: 1,
+		},
+		{
+			name:          "conflict next to a clean change",
+			base:          "a\nb\nc\nd\ne\n",
+			ours:          "A\nb\nc\nuser\ne\n",
+			theirs:        "a\nb\nc\ntemplate\ne\n",
+			want:          "A\nb\nc\n<<<<<<< project\nuser\n=======\ntemplate\n>>>>>>> template\ne\n",
+			wantConflicts: 1,
+		},
+		{
+			name:          "two conflicts",
+			base:          "a\nb\nc\nd\ne\n",
+			ours:          "a\nb1\nc\nd1\ne\n",
+			theirs:        "a\nb2\nc\nd2\ne\n",
+			want:          "a\n<<<<<<< project\nb1\n=======\nb2\n>>>>>>> template\nc\n<<<<<<< project\nd1\n=======\nd2\n>>>>>>> template\ne\n",
+			wantConflicts: 2,
+		},
+		{
+			name:          "deleted in ours, changed in theirs",
+			base:          "a\nb\nc\n",
+			ours:          "a\nc\n",
+			theirs:        "a\nB\nc\n",
+			want:          "a\n<<<<<<< project\n=======\nB\n>>>>>>> template\nc\n",
+			wantConflicts: 1,
+		},
+		{
+			name:   "empty base, same content",
+			base:   "",
+			ours:   "a\nb\n",
+			theirs: "a\nb\n",
+			want:   "a\nb\n",
+		},
+		{
+			name:          "empty base, different content",
+			base:          "",
+			ours:          "a\n",
+			theirs:        "b\n",
+			want:          "<<<<<<< project\na\n=======\nb\n>>>>>>> template\n",
+			wantConflicts: 1,
+		},
+		{
+			name:   "empty base, only theirs added content",
+			base:   "",
+			ours:   "",
+			theirs: "a\n",
+			want:   "a\n",
+		},
+		{
+			name:   "everything removed",
+			base:   "a\n",
+			ours:   "",
+			theirs: "a\n",
+			want:   "",
+		},
+		{
+			name:   "missing trailing newline",
+			base:   "a\nb",
+			ours:   "a\nb",
+			theirs: "a\nb\nc",
+			want:   "a\nb\nc\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, conflicts := Merge(tt.base, tt.ours, tt.theirs, "project", "template")
+			if got != tt.want {
+				t.Errorf("Merge() =\n%s\nwant\n%s", got, tt.want)
+			}
+			if conflicts != tt.wantConflicts {
+				t.Errorf("Merge() conflicts = %d, want %d", conflicts, tt.wantConflicts)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package generation
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"craft/internal/constants"
+	"craft/internal/container"
+	"craft/internal/templating"
+	"craft/internal/utils"
+)
+
+// FileName is the generation manifest inside the constants.CraftDir of a generated project.
+const FileName = "manifest.json"
+
+// Manifest records how a project was generated, so other tooling (craft update, audits, ...) can reason about it later.
+type Manifest struct {
+	CraftVersion string    `json:"craftVersion"`
+	GeneratedAt  time.Time `json:"generatedAt"`
+	Template     Template  `json:"template"`
+	ProjectName  string    `json:"projectName"`
+	Language     string    `json:"language"`
+	Dependencies []string  `json:"dependencies"`
+	BuildTool    string    `json:"buildTool,omitempty"`
+	Framework    string    `json:"framework,omitempty"`
+	Stacks       []string  `json:"stacks,omitempty"`
+	Kind         string    `json:"kind,omitempty"`
+	ModulePath   string    `json:"modulePath"`
+	Author       string    `json:"author"`
+	// Runtime is the container runtime the generated files use.
+	Runtime string `json:"runtime,omitempty"`
+	// NoContainers is set if the skeletons of the templates were rendered instead of running their containers.
+	NoContainers bool `json:"noContainers,omitempty"`
+	// Versions and Variables hold the values the templates were rendered with.
+	Versions  map[string]string `json:"versions"`
+	Variables map[string]string `json:"variables"`
+	// Files maps the path of every generated file (relative to the project) to the hash of its generated content.
+	Files map[string]string `json:"files"`
+	// Components lists the components added with 'craft add'.
+	Components []Component `json:"components,omitempty"`
+}
+
+// Component records a component added to the project.
+type Component struct {
+	Template
+	AddedAt   time.Time         `json:"addedAt"`
+	Variables map[string]string `json:"variables"`
+}
+
+// Template identifies the template a project was generated from.
+type Template struct {
+	// ID is the name from the template manifest.
+	ID string `json:"id"`
+	// Path is the template directory inside its source.
+	Path string `json:"path"`
+	// Source is "built-in", a template directory or the url of a git repository.
+	Source string `json:"source"`
+	// Ref and Revision are the requested and the resolved revision of a git repository.
+	// For built-in templates the revision is the craft version.
+	Ref      string `json:"ref,omitempty"`
+	Revision string `json:"revision,omitempty"`
+}
+
+// Context returns the template context the project was generated with.
+func (m *Manifest) Context() templating.Context {
+	ctx := templating.NewContext(m.ProjectName, m.Language, m.Dependencies)
+	ctx.BuildTool = m.BuildTool
+	ctx.Framework = m.Framework
+	ctx.Stacks = m.Stacks
+	// rust projects generated before the stacks were recorded kept them in the framework, e.g. "axum,tokio"
+	if m.Stacks == nil && m.Language == "rust" && m.Framework != "" {
+		ctx.Stacks = strings.Split(m.Framework, ",")
+		ctx.Framework = ""
+	}
+	ctx.Kind = m.Kind
+	ctx.ModulePath = m.ModulePath
+	// projects generated before the runtime was recorded use docker
+	ctx.Runtime = container.Docker
+	if m.Runtime != "" {
+		ctx.Runtime = m.Runtime
+	}
+	if m.Author != "" {
+		ctx.Author = m.Author
+	}
+	ctx.NoContainers = m.NoContainers
+	return ctx
+}
+
+// AddComponent records a component, replacing an earlier record of the same component.
+func (m *Manifest) AddComponent(component Component) {
+	for i, existing := range m.Components {
+		if existing.ID == component.ID {
+			m.Components[i] = component
+			return
+		}
+	}
+	m.Components = append(m.Components, component)
+}
+
+// Path returns the path of the generation manifest in projectDir.
+func Path(projectDir string) string {
+	return filepath.Join(projectDir, constants.CraftDir, FileName)
+}
+
+// Load reads the generation manifest of the project in projectDir.
+func Load(projectDir string) (*Manifest, error) {
+	data, err := utils.ReadFile(Path(projectDir))
+	if err != nil {
+		return nil, fmt.Errorf("error reading the generation manifest, was the project generated by %s? %v", constants.ToolName, err)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", Path(projectDir), err)
+	}
+	return &m, nil
+}
+
+// Write writes the generation manifest into projectDir.
+func Write(projectDir string, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding the generation manifest: %v", err)
+	}
+	return utils.WriteFile(Path(projectDir), append(data, '\n'))
+}
+
+// Hash returns the content hash recorded for a file.
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// HashFiles hashes all files in dir, except for the ones in the constants.CraftDir.
+func HashFiles(dir string) (map[string]string, error) {
+	files, err := utils.ListFilesRecursive(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make(map[string]string, len(files))
+	for _, file := range files {
+		if file == constants.CraftDir || strings.HasPrefix(file, constants.CraftDir+string(filepath.Separator)) {
+			continue
+		}
+
+		data, err := utils.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			return nil, fmt.Errorf("error hashing %s: %v", file, err)
+		}
+		hashes[filepath.ToSlash(file)] = Hash(data)
+	}
+	return hashes, nil
+}
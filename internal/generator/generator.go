@@ -0,0 +1,831 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"craft/internal/common"
+	"craft/internal/constants"
+	"craft/internal/container"
+	"craft/internal/generation"
+	"craft/internal/manifest"
+	"craft/internal/templating"
+	"craft/internal/utils"
+)
+
+// containerWorkspace is where the project directory is mounted into the containers of the manifest.
+const containerWorkspace = "/workspace"
+
+// Generator creates a project from a template directory by running the pipeline declared in its manifest:
+//
+//	copy files -> run scripts -> run containers (or render the skeleton) -> prune & hoist script output -> render templates -> render repeats -> rename DOT files -> merge files -> delete files -> move files -> print messages
+type Generator struct {
+	TemplatesFileSystem fs.FS
+	TemplatePath        string
+	Context             templating.Context
+	Options             common.Options
+
+	runtime    container.Runtime
+	runtimeErr error
+}
+
+// LoadManifest loads the manifest of the generator's template directory.
+func (g *Generator) LoadManifest() (*manifest.Manifest, error) {
+	return manifest.Load(g.TemplatesFileSystem, g.TemplatePath)
+}
+
+// Run generates the project into a new directory named after the project in the current working directory.
+// The project is generated in a staging directory which is only moved into place if every step succeeded,
+// a failed generation is removed again (or kept with --keep-failed).
+// With --into the files are generated into an existing directory instead, see runInto.
+// In a dry run every change is only recorded and the plan is printed instead.
+func (g *Generator) Run() error {
+	m, err := g.LoadManifest()
+	if err != nil {
+		return err
+	}
+
+	if err := g.prepareContext(m); err != nil {
+		return err
+	}
+
+	renderFiles, err := g.renderFiles(m)
+	if err != nil {
+		return err
+	}
+
+	if g.Options.DryRun {
+		return g.dryRun(m, renderFiles)
+	}
+
+	if g.Options.Into != "" {
+		return g.runInto(m, renderFiles)
+	}
+
+	stagingDir, projectHostDir, err := utils.PrepareStagingDir(g.Context.ProjectName)
+	if err != nil {
+		return err
+	}
+
+	if err := g.generate(m, renderFiles, stagingDir); err != nil {
+		return g.rollback(stagingDir, err)
+	}
+
+	if err := g.RecordGeneration(m, stagingDir, stagingDir); err != nil {
+		return g.rollback(stagingDir, err)
+	}
+
+	if err := utils.CommitStagingDir(stagingDir, projectHostDir); err != nil {
+		return g.rollback(stagingDir, err)
+	}
+
+	fmt.Printf("A new project folder with the name %v was created\n", g.Context.ProjectName)
+	fmt.Printf("The project directory: %v\n\n", projectHostDir)
+
+	return g.printMessages(m)
+}
+
+// runInto generates the project in a temporary staging directory and copies the files into the existing
+// directory --into. Files that already exist there are resolved with the --conflict strategy.
+func (g *Generator) runInto(m *manifest.Manifest, renderFiles []string) error {
+	targetDir, err := intoDir(g.Options.Into)
+	if err != nil {
+		return err
+	}
+
+	stagingDir, err := utils.PrepareTempStagingDir()
+	if err != nil {
+		return err
+	}
+
+	if err := g.generate(m, renderFiles, stagingDir); err != nil {
+		return g.rollback(stagingDir, err)
+	}
+
+	changes, err := g.applyInto(m, stagingDir, targetDir, newConflictResolver(g.Options.Conflict, true))
+	// the summary is also printed on failure, as the files before the failing one were already copied
+	printSummary(targetDir, changes)
+	if err != nil {
+		return g.rollback(stagingDir, err)
+	}
+
+	// the generation manifest is not a conflict, it always describes the latest generation
+	if err := g.RecordGeneration(m, stagingDir, targetDir); err != nil {
+		return g.rollback(stagingDir, err)
+	}
+
+	if err := utils.RemoveFileFromHost(stagingDir); err != nil {
+		return fmt.Errorf("error removing the staging directory %v: %v", stagingDir, err)
+	}
+	fmt.Println()
+
+	return g.printMessages(m)
+}
+
+// intoDir returns the absolute path of the existing directory to generate into.
+func intoDir(into string) (string, error) {
+	targetDir, err := filepath.Abs(into)
+	if err != nil {
+		return "", fmt.Errorf("invalid directory %s: %v", into, err)
+	}
+
+	info, err := os.Stat(targetDir)
+	if err != nil || !info.IsDir() {
+		return "", fmt.Errorf("the directory %s to generate into does not exist", targetDir)
+	}
+	return targetDir, nil
+}
+
+// Render generates the files of the template into the existing directory dir, without the generation manifest.
+// It is used to compare template versions, e.g. by craft update.
+func (g *Generator) Render(dir string) (*manifest.Manifest, error) {
+	m, err := g.LoadManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := g.prepareContext(m); err != nil {
+		return nil, err
+	}
+
+	renderFiles, err := g.renderFiles(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, g.generate(m, renderFiles, dir)
+}
+
+// dryRun records the generation into the project directory without touching the disk and prints the plan.
+func (g *Generator) dryRun(m *manifest.Manifest, renderFiles []string) error {
+	recorder := utils.NewRecorder()
+	defer utils.SetOperator(recorder)()
+
+	if g.Options.Into != "" {
+		targetDir, err := intoDir(g.Options.Into)
+		if err != nil {
+			return err
+		}
+
+		stagingDir, err := utils.PrepareTempStagingDir()
+		if err != nil {
+			return err
+		}
+
+		if err := g.generate(m, renderFiles, stagingDir); err != nil {
+			return err
+		}
+
+		g.printPlan(m, recorder, stagingDir, renderFiles, fmt.Sprintf("The files would be generated for %v:", targetDir))
+
+		changes, err := g.applyInto(m, stagingDir, targetDir, newConflictResolver(g.Options.Conflict, false))
+		printSummary(targetDir, changes)
+		if err != nil {
+			return err
+		}
+		return g.RecordGeneration(m, stagingDir, targetDir)
+	}
+
+	projectHostDir, err := utils.PrepareProjectDir(g.Context.ProjectName)
+	if err != nil {
+		return err
+	}
+
+	if err := g.generate(m, renderFiles, projectHostDir); err != nil {
+		return err
+	}
+
+	if err := g.RecordGeneration(m, projectHostDir, projectHostDir); err != nil {
+		return err
+	}
+
+	g.printPlan(m, recorder, projectHostDir, renderFiles, fmt.Sprintf("The project directory %v would be created with:", projectHostDir))
+	return nil
+}
+
+// generate runs all steps of the manifest in projectHostDir.
+func (g *Generator) generate(m *manifest.Manifest, renderFiles []string, projectHostDir string) error {
+	if err := utils.CopyDirFromFSExcluding(g.TemplatesFileSystem, g.TemplatePath, projectHostDir, notCopied(m, renderFiles)); err != nil {
+		return fmt.Errorf("error copying files from template path: %v", err)
+	}
+
+	if err := g.runScripts(m, projectHostDir); err != nil {
+		return err
+	}
+
+	if err := g.runContainers(m, projectHostDir); err != nil {
+		return err
+	}
+
+	if err := g.hoist(m, projectHostDir); err != nil {
+		return err
+	}
+
+	for _, file := range renderFiles {
+		if err := templating.RenderFile(g.TemplatesFileSystem, g.TemplatePath, file, projectHostDir, g.Context); err != nil {
+			return fmt.Errorf("error rendering template files: %v", err)
+		}
+	}
+
+	if err := g.repeat(m, projectHostDir); err != nil {
+		return err
+	}
+
+	if err := g.renameDotFiles(projectHostDir, renderFiles); err != nil {
+		return err
+	}
+
+	if err := g.merge(m, projectHostDir); err != nil {
+		return err
+	}
+
+	deleted, err := g.renderPaths(m.Delete)
+	if err != nil {
+		return err
+	}
+	if err := common.CleanupFiles(projectHostDir, deleted); err != nil {
+		return err
+	}
+
+	return g.move(m, projectHostDir)
+}
+
+// notCopied returns the files and directories of the template that are not copied into the project as they are.
+func notCopied(m *manifest.Manifest, renderFiles []string) []string {
+	// .git is only present in templates fetched from a repository
+	excluded := append([]string{manifest.FileName, ".git"}, renderFiles...)
+	for _, repeat := range m.Repeats {
+		excluded = append(excluded, filepath.Clean(repeat.Source))
+	}
+	if m.Skeleton != nil {
+		excluded = append(excluded, filepath.Clean(m.Skeleton.Source))
+	}
+	return excluded
+}
+
+// rollback removes the staging directory of a failed generation, unless it should be kept for debugging.
+func (g *Generator) rollback(stagingDir string, cause error) error {
+	if g.Options.KeepFailed {
+		fmt.Printf("The failed generation was kept in %v\n", stagingDir)
+		return cause
+	}
+
+	if err := utils.RemoveFileFromHost(stagingDir); err != nil {
+		return fmt.Errorf("%v (the staging directory %v could not be removed: %v)", cause, stagingDir, err)
+	}
+	return cause
+}
+
+// prepareContext adds the manifest versions, the resolved variables and the container runtime to the context.
+// Versions set by the handler take precedence over the ones declared in the manifest.
+func (g *Generator) prepareContext(m *manifest.Manifest) error {
+	if g.Context.Versions == nil {
+		g.Context.Versions = map[string]string{}
+	}
+	for tool, version := range m.Versions {
+		if _, ok := g.Context.Versions[tool]; !ok {
+			g.Context.Versions[tool] = version
+		}
+	}
+
+	variables, err := m.ResolveVariables(g.Options.Variables)
+	if err != nil {
+		return err
+	}
+	g.Context.Variables = variables
+
+	// the generated files use the runtime that generates the project, docker if none is available.
+	// A runtime already set (e.g. the recorded one of an updated project) is kept.
+	if g.Context.Runtime == "" {
+		runtime, err := g.containerRuntime()
+		switch {
+		case err == nil:
+			g.Context.Runtime = runtime.Name()
+		case g.Options.Runtime != "":
+			return err
+		default:
+			g.Context.Runtime = container.Docker
+		}
+	}
+	return nil
+}
+
+func (g *Generator) renderFiles(m *manifest.Manifest) ([]string, error) {
+	if len(m.Render) > 0 {
+		return m.Render, nil
+	}
+
+	files, err := templating.ListTemplateFiles(g.TemplatesFileSystem, g.TemplatePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// the files of the repeats are rendered once for every item instead, the skeleton only without containers
+	rendered := make([]string, 0, len(files))
+	for _, file := range files {
+		if !isInRenderedDir(m, file) {
+			rendered = append(rendered, file)
+		}
+	}
+	return rendered, nil
+}
+
+// isInRenderedDir reports whether the file (relative to the template directory) belongs to the source of a repeat or of the skeleton.
+func isInRenderedDir(m *manifest.Manifest, file string) bool {
+	var sources []string
+	for _, repeat := range m.Repeats {
+		sources = append(sources, repeat.Source)
+	}
+	if m.Skeleton != nil {
+		sources = append(sources, m.Skeleton.Source)
+	}
+
+	for _, source := range sources {
+		if strings.HasPrefix(filepath.Clean(file), filepath.Clean(source)+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Generator) runScripts(m *manifest.Manifest, projectHostDir string) error {
+	for _, script := range m.Scripts {
+		args := make([]string, 0, len(script.Args))
+		for _, arg := range script.Args {
+			rendered, err := templating.RenderString(arg, g.Context)
+			if err != nil {
+				return err
+			}
+			args = append(args, rendered)
+		}
+
+		scriptPath := filepath.Join(projectHostDir, script.Path)
+		if err := utils.ExecuteScript(scriptPath, projectHostDir, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runContainers builds the generator images of the manifest and runs them with the project directory mounted.
+// The output of cached containers is restored from the cache if it is there, without a container runtime.
+// A dry run only records them. With --no-docker, or if no runtime was chosen and none is available,
+// the skeleton of the manifest is rendered instead, as it is for Options.Skeleton.
+func (g *Generator) runContainers(m *manifest.Manifest, projectHostDir string) error {
+	if len(m.Containers) == 0 {
+		return nil
+	}
+
+	if g.Options.NoDocker || g.Options.Skeleton {
+		return g.withoutContainers(m, projectHostDir, nil)
+	}
+
+	runs := make([]containerRun, 0, len(m.Containers))
+	needsRuntime := false
+	for _, c := range m.Containers {
+		run, err := g.prepareContainer(m, c, projectHostDir)
+		if err != nil {
+			return err
+		}
+		runs = append(runs, run)
+		needsRuntime = needsRuntime || !g.isCached(run)
+	}
+
+	if recorder, dryRun := utils.ActiveRecorder(); dryRun {
+		for _, run := range runs {
+			recorder.RecordContainer(filepath.Join(projectHostDir, run.Dockerfile), run.Image, run.command, projectHostDir)
+		}
+		return nil
+	}
+
+	ctx := context.Background()
+	var runtime container.Runtime
+	if needsRuntime {
+		var err error
+		if runtime, err = g.availableRuntime(ctx); err != nil {
+			// only a detected runtime falls back, one chosen with --runtime has to work
+			if g.Options.Runtime == "" && container.IsUnavailable(err) {
+				return g.withoutContainers(m, projectHostDir, err)
+			}
+			return err
+		}
+		if container.InContainer() {
+			fmt.Printf("craft runs inside a container, %s mounts %s from its own host\n", runtime.Name(), projectHostDir)
+		}
+	}
+
+	progress := container.PrintProgress(os.Stdout)
+	for _, run := range runs {
+		if g.isCached(run) {
+			if err := g.restoreOutput(run, projectHostDir); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := g.buildImage(ctx, runtime, m, run, projectHostDir, progress); err != nil {
+			return err
+		}
+
+		if run.archive != "" {
+			if err := g.runCached(ctx, runtime, run, projectHostDir, progress); err != nil {
+				return err
+			}
+		} else {
+			fmt.Printf("Running the image %s with %s\n", run.Image, runtime.Name())
+			options := container.RunOptions{
+				Image: run.Image,
+				Cmd:   run.command,
+				User:  container.UserOf(runtime),
+				Binds: []string{projectHostDir + ":" + containerWorkspace},
+			}
+			if err := runtime.Run(ctx, options, progress); err != nil {
+				return err
+			}
+		}
+		// the last use is only needed by 'craft cache prune', it must not fail the generation
+		_ = container.RecordUse(run.Image)
+	}
+	return nil
+}
+
+// buildImage builds the image of the container with the project directory as build context.
+// The image is labelled as an image of craft built for the template, see 'craft cache'.
+func (g *Generator) buildImage(ctx context.Context, runtime container.Runtime, m *manifest.Manifest, run containerRun, contextDir string, progress container.Progress) error {
+	buildArgs := make(map[string]string, len(run.buildArgs)+2)
+	for name, value := range run.buildArgs {
+		buildArgs[name] = value
+	}
+
+	// the generator images hand the created files over to this user, rootless runtimes map their root to the current user
+	uid, gid := runtime.User()
+	buildArgs["UID"] = strconv.Itoa(uid)
+	buildArgs["GID"] = strconv.Itoa(gid)
+
+	fmt.Printf("Building the image %s with %s\n", run.Image, runtime.Name())
+	build := container.BuildOptions{
+		ContextDir: contextDir,
+		Dockerfile: run.Dockerfile,
+		Tag:        run.Image,
+		BuildArgs:  buildArgs,
+		Labels:     container.ImageLabels(m.Name),
+	}
+	return runtime.Build(ctx, build, progress)
+}
+
+// availableRuntime returns the runtime of the containers once it answered.
+func (g *Generator) availableRuntime(ctx context.Context) (container.Runtime, error) {
+	runtime, err := g.containerRuntime()
+	if err != nil {
+		return nil, err
+	}
+	if err := runtime.Ping(ctx); err != nil {
+		return nil, err
+	}
+	return runtime, nil
+}
+
+// withoutContainers renders the skeleton of the manifest instead of running its containers (--no-docker),
+// optional containers are skipped. cause is the reason no container runtime is used if it was not --no-docker.
+// With Options.Skeleton the containers are skipped even without a skeleton and the context is not changed.
+func (g *Generator) withoutContainers(m *manifest.Manifest, projectHostDir string, cause error) error {
+	if m.Skeleton == nil && !g.Options.Skeleton {
+		for _, c := range m.Containers {
+			if c.Optional {
+				continue
+			}
+			if cause != nil {
+				return cause
+			}
+			return fmt.Errorf("the template '%s' has no skeleton, it needs a container runtime to build %s", m.Name, c.Image)
+		}
+	}
+
+	if cause != nil {
+		fmt.Printf("No container runtime is available (%v), the project is generated without containers\n", cause)
+	}
+	if !g.Options.Skeleton {
+		g.Context.NoContainers = true
+	}
+	if m.Skeleton == nil {
+		return nil
+	}
+
+	target, err := templating.RenderString(m.Skeleton.Target, g.Context)
+	if err != nil {
+		return err
+	}
+	targetDir, err := utils.ContainedPath(projectHostDir, strings.TrimSpace(target))
+	if err != nil {
+		return fmt.Errorf("invalid skeleton target: %v", err)
+	}
+
+	if err := g.renderDir(m.Skeleton.Source, targetDir, g.Context); err != nil {
+		return fmt.Errorf("error rendering the skeleton %s: %v", m.Skeleton.Source, err)
+	}
+	return movePaths(m.Skeleton.Moves, g.Context, targetDir)
+}
+
+// containerRuntime returns the runtime of --runtime or of the context, or detects the first available one.
+// The detection only runs once, also if it failed.
+func (g *Generator) containerRuntime() (container.Runtime, error) {
+	if g.runtime == nil && g.runtimeErr == nil {
+		name := g.Options.Runtime
+		if name == "" {
+			name = g.Context.Runtime
+		}
+		g.runtime, g.runtimeErr = container.Detect(context.Background(), name)
+	}
+	return g.runtime, g.runtimeErr
+}
+
+// hoist removes the pruned files from the directory the scripts created and moves its content into the project root.
+func (g *Generator) hoist(m *manifest.Manifest, projectHostDir string) error {
+	if m.Hoist == "" {
+		return nil
+	}
+
+	hoistDir, err := templating.RenderString(m.Hoist, g.Context)
+	if err != nil {
+		return err
+	}
+	generatedPath, err := utils.ContainedPath(projectHostDir, strings.TrimSpace(hoistDir))
+	if err != nil {
+		return fmt.Errorf("invalid hoist directory: %v", err)
+	}
+	if generatedPath == filepath.Clean(projectHostDir) {
+		return fmt.Errorf("invalid hoist directory '%s': it is the project directory itself", hoistDir)
+	}
+
+	pruned, err := g.renderPaths(m.Prune)
+	if err != nil {
+		return err
+	}
+	if err := common.CleanupFiles(generatedPath, pruned); err != nil {
+		return err
+	}
+
+	return utils.CopyAllOnePathUpAndRemoveDir(generatedPath)
+}
+
+// renameDotFiles renames the files and directories in the template root whose name starts with DOT (e.g. DOTgitignore or DOTgithub),
+// hidden files can't be embedded into the binary. Rendered files are renamed without their template suffix.
+func (g *Generator) renameDotFiles(projectHostDir string, renderFiles []string) error {
+	entries, err := fs.ReadDir(g.TemplatesFileSystem, g.TemplatePath)
+	if err != nil {
+		return fmt.Errorf("error reading directory: %v", err)
+	}
+
+	var dotFiles []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, constants.DotFileNotationPrefix) {
+			continue
+		}
+		if utils.Contains(renderFiles, name) {
+			name = strings.TrimSuffix(name, constants.TemplateFileSuffix)
+		}
+		dotFiles = append(dotFiles, name)
+	}
+
+	if err := utils.RenameFilesWithPrefix(dotFiles, projectHostDir, constants.DotFileNotationPrefix, constants.DotFilePrefix); err != nil {
+		fmt.Printf("Error renaming dot files: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+func (g *Generator) merge(m *manifest.Manifest, projectHostDir string) error {
+	for _, merge := range m.Merges {
+		placeholder, err := templating.RenderString(merge.Placeholder, g.Context)
+		if err != nil {
+			return err
+		}
+
+		source, err := utils.ContainedPath(projectHostDir, merge.Source)
+		if err != nil {
+			return fmt.Errorf("invalid merge: %v", err)
+		}
+		target, err := utils.ContainedPath(projectHostDir, merge.Target)
+		if err != nil {
+			return fmt.Errorf("invalid merge: %v", err)
+		}
+
+		data, err := utils.ReadFile(source)
+		if err != nil {
+			return fmt.Errorf("error reading %s to merge it into %s: %v", merge.Source, merge.Target, err)
+		}
+
+		if err := utils.ChangeWordInFile(target, placeholder, string(data), false); err != nil {
+			return fmt.Errorf("error merging %s into %s: %v", merge.Source, merge.Target, err)
+		}
+	}
+	return nil
+}
+
+// repeat renders the source directory of every repeat once for each of its items into the rendered target.
+// Files ending with constants.TemplateFileSuffix are rendered, all others are copied.
+func (g *Generator) repeat(m *manifest.Manifest, projectHostDir string) error {
+	for _, repeat := range m.Repeats {
+		items, err := templating.RenderString(repeat.Items, g.Context)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range templating.Split(",", items) {
+			ctx := g.Context
+			ctx.Item = item
+
+			target, err := templating.RenderString(repeat.Target, ctx)
+			if err != nil {
+				return err
+			}
+			targetDir, err := utils.ContainedPath(projectHostDir, strings.TrimSpace(target))
+			if err != nil {
+				return fmt.Errorf("invalid target of %s for '%s': %v", repeat.Source, item, err)
+			}
+
+			if err := g.renderDir(repeat.Source, targetDir, ctx); err != nil {
+				return fmt.Errorf("error rendering %s for '%s': %v", repeat.Source, item, err)
+			}
+
+			if err := movePaths(repeat.Moves, ctx, targetDir); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// renderDir renders the files of the template directory source (relative to the template) into targetDir,
+// files ending with constants.TemplateFileSuffix are rendered with ctx and the others are copied.
+func (g *Generator) renderDir(source, targetDir string, ctx templating.Context) error {
+	sourceDir := path.Join(g.TemplatePath, filepath.ToSlash(source))
+
+	return fs.WalkDir(g.TemplatesFileSystem, sourceDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		relPath, err := filepath.Rel(sourceDir, p)
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(relPath, constants.TemplateFileSuffix) {
+			return templating.RenderFile(g.TemplatesFileSystem, sourceDir, relPath, targetDir, ctx)
+		}
+		return utils.CopyFileFromFS(g.TemplatesFileSystem, p, filepath.Join(targetDir, relPath))
+	})
+}
+
+// move relocates the files and directories of the moves.
+func (g *Generator) move(m *manifest.Manifest, projectHostDir string) error {
+	return movePaths(m.Moves, g.Context, projectHostDir)
+}
+
+// movePaths renders and applies the moves inside dir, a move whose target is its source does nothing.
+func movePaths(moves []manifest.Move, ctx templating.Context, dir string) error {
+	for _, move := range moves {
+		source, err := templating.RenderString(move.Source, ctx)
+		if err != nil {
+			return err
+		}
+		target, err := templating.RenderString(move.Target, ctx)
+		if err != nil {
+			return err
+		}
+		source, target = strings.TrimSpace(source), strings.TrimSpace(target)
+		if source == "" || filepath.Clean(source) == filepath.Clean(target) {
+			continue
+		}
+
+		sourcePath, err := utils.ContainedPath(dir, source)
+		if err != nil {
+			return fmt.Errorf("invalid move: %v", err)
+		}
+		targetPath, err := utils.ContainedPath(dir, target)
+		if err != nil {
+			return fmt.Errorf("invalid move: %v", err)
+		}
+		if err := utils.MovePath(sourcePath, targetPath); err != nil {
+			return fmt.Errorf("error moving %s to %s: %v", source, target, err)
+		}
+	}
+	return nil
+}
+
+// renderPaths renders the paths of the manifest, paths that render to an empty string (e.g. by a false condition) are left out.
+func (g *Generator) renderPaths(paths []string) ([]string, error) {
+	rendered := make([]string, 0, len(paths))
+	for _, path := range paths {
+		value, err := templating.RenderString(path, g.Context)
+		if err != nil {
+			return nil, err
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			rendered = append(rendered, value)
+		}
+	}
+	return rendered, nil
+}
+
+// RecordGeneration writes the generation manifest into projectDir, with the hashes of the files generated in generatedDir.
+// Components are recorded in the generation manifest of the project they were added to instead.
+func (g *Generator) RecordGeneration(m *manifest.Manifest, generatedDir, projectDir string) error {
+	if m.IsComponent() {
+		return g.recordComponent(m, projectDir)
+	}
+
+	files, err := generation.HashFiles(generatedDir)
+	if err != nil {
+		return err
+	}
+
+	dependencies := g.Context.Dependencies
+	if dependencies == nil {
+		dependencies = []string{}
+	}
+
+	return generation.Write(projectDir, &generation.Manifest{
+		CraftVersion: constants.Version,
+		GeneratedAt:  time.Now().UTC().Truncate(time.Second),
+		Template:     g.templateRecord(m),
+		ProjectName:  g.Context.ProjectName,
+		Language:     g.Context.Language,
+		Dependencies: dependencies,
+		BuildTool:    g.Context.BuildTool,
+		Framework:    g.Context.Framework,
+		Stacks:       g.Context.Stacks,
+		Kind:         g.Context.Kind,
+		ModulePath:   g.Context.ModulePath,
+		Author:       g.Context.Author,
+		Runtime:      g.Context.Runtime,
+		NoContainers: g.Context.NoContainers,
+		Versions:     g.Context.Versions,
+		Variables:    g.Context.Variables,
+		Files:        files,
+	})
+}
+
+// recordComponent adds the component to the generation manifest of the project.
+// Projects generated before generation manifests existed have none, nothing is recorded then.
+func (g *Generator) recordComponent(m *manifest.Manifest, projectDir string) error {
+	if !utils.FileExists(generation.Path(projectDir)) {
+		return nil
+	}
+
+	recorded, err := generation.Load(projectDir)
+	if err != nil {
+		return err
+	}
+
+	recorded.AddComponent(generation.Component{
+		Template:  g.templateRecord(m),
+		AddedAt:   time.Now().UTC().Truncate(time.Second),
+		Variables: g.Context.Variables,
+	})
+	return generation.Write(projectDir, recorded)
+}
+
+// templateRecord identifies the generator's template for the generation manifest.
+func (g *Generator) templateRecord(m *manifest.Manifest) generation.Template {
+	template := generation.Template{
+		ID:       m.Name,
+		Path:     g.TemplatePath,
+		Source:   "built-in",
+		Revision: constants.Version,
+	}
+	if layered, ok := g.TemplatesFileSystem.(interface{ Source(name string) string }); ok {
+		template.Source = layered.Source(g.TemplatePath)
+	}
+	if template.Source != "built-in" {
+		template.Revision = ""
+	}
+	if source := g.Options.TemplateSource; source != nil {
+		template.Source = source.URL
+		template.Ref = source.Ref
+		template.Revision = source.Commit
+	}
+	return template
+}
+
+func (g *Generator) printMessages(m *manifest.Manifest) error {
+	for _, message := range m.Messages {
+		rendered, err := templating.RenderString(message, g.Context)
+		if err != nil {
+			return err
+		}
+		// e.g. a message about the image of a container that did not run
+		if strings.TrimSpace(rendered) == "" {
+			continue
+		}
+		fmt.Println(rendered)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"craft/internal/common"
+	"craft/internal/container"
+	"craft/internal/templating"
+)
+
+// inTempDir changes into a new temporary directory for the test, projects are generated into the working directory.
+func inTempDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatal(err)
+		}
+	})
+	return dir
+}
+
+// stagingDirs returns the staging directories left in dir.
+func stagingDirs(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var staging []string
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".craft-staging-") {
+			staging = append(staging, entry.Name())
+		}
+	}
+	return staging
+}
+
+func newTestGenerator(fsys fstest.MapFS, options common.Options) *Generator {
+	ctx := templating.NewContext("orders", "go", nil)
+	ctx.Runtime = container.Docker
+	return &Generator{TemplatesFileSystem: fsys, TemplatePath: "app", Context: ctx, Options: options}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       map[string]string
+		keepFailed  bool
+		wantErr     string
+		wantStaging int
+	}{
+		{
+			name:  "generated",
+			files: map[string]string{"README.md.template": "# {{ .ProjectName }}\n"},
+		},
+		{
+			name:    "failing render",
+			files:   map[string]string{"README.md.template": "# {{ .Missing }}\n"},
+			wantErr: "error rendering template files",
+		},
+		{
+			name:    "failing script",
+			files:   map[string]string{"template.yaml": "name: app\nlanguage: go\nscripts:\n  - path: setup.sh\n", "setup.sh": "#!/bin/sh\nexit 1\n"},
+			wantErr: "error executing script",
+		},
+		{
+			name:        "failing render with --keep-failed",
+			files:       map[string]string{"README.md.template": "# {{ .Missing }}\n"},
+			keepFailed:  true,
+			wantErr:     "error rendering template files",
+			wantStaging: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := inTempDir(t)
+			fsys := fstest.MapFS{"app/template.yaml": {Data: []byte("name: app\nlanguage: go\n")}}
+			for name, content := range tt.files {
+				fsys["app/"+name] = &fstest.MapFile{Data: []byte(content)}
+			}
+
+			err := newTestGenerator(fsys, common.Options{KeepFailed: tt.keepFailed}).Run()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Run() error = %v", err)
+				}
+				content, err := os.ReadFile(filepath.Join(dir, "orders", "README.md"))
+				if err != nil || string(content) != "# orders\n" {
+					t.Errorf("README.md = %q, %v", content, err)
+				}
+			} else {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Run() error = %v, want %q", err, tt.wantErr)
+				}
+				if _, err := os.Stat(filepath.Join(dir, "orders")); !os.IsNotExist(err) {
+					t.Error("the project directory of a failed generation exists")
+				}
+			}
+
+			if staging := stagingDirs(t, dir); len(staging) != tt.wantStaging {
+				t.Errorf("staging directories = %q, want %d", staging, tt.wantStaging)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package generator
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"craft/internal/constants"
+	"craft/internal/diff"
+	"craft/internal/manifest"
+	"craft/internal/patch"
+	"craft/internal/utils"
+)
+
+// Strategies for generated files that already exist in the target directory (--conflict).
+const (
+	ConflictAsk       = "ask"
+	ConflictSkip      = "skip"
+	ConflictOverwrite = "overwrite"
+	ConflictKeepBoth  = "keep-both"
+)
+
+// ConflictStrategies lists all valid values of --conflict.
+var ConflictStrategies = []string{ConflictAsk, ConflictSkip, ConflictOverwrite, ConflictKeepBoth}
+
+// KeepBothSuffix is appended to the generated version of a conflicting file that is kept next to the existing one.
+const KeepBothSuffix = ".craft-new"
+
+// Actions taken for a generated file, used in the summary.
+const (
+	actionCreated     = "created"
+	actionOverwritten = "overwritten"
+	actionSkipped     = "skipped"
+	actionKeptBoth    = "kept both"
+	actionUnchanged   = "unchanged"
+	actionConflict    = "conflict"
+	actionPatched     = "patched"
+)
+
+type change struct {
+	path   string
+	action string
+}
+
+// conflictResolver decides what happens with generated files that already exist in the target directory.
+type conflictResolver struct {
+	strategy string
+	// interactive is false in a dry run, conflicts are only reported then
+	interactive bool
+	in          *bufio.Reader
+	out         io.Writer
+}
+
+func newConflictResolver(strategy string, interactive bool) *conflictResolver {
+	if strategy == "" {
+		strategy = ConflictAsk
+	}
+	return &conflictResolver{strategy: strategy, interactive: interactive, in: bufio.NewReader(os.Stdin), out: os.Stdout}
+}
+
+// resolve returns the strategy for the conflicting file path, asking the user if no global strategy was chosen.
+func (r *conflictResolver) resolve(path string, existing, generated []byte) (string, error) {
+	if r.strategy != ConflictAsk {
+		return r.strategy, nil
+	}
+	if !r.interactive {
+		return ConflictAsk, nil
+	}
+
+	for {
+		fmt.Fprintf(r.out, "%s already exists. [s]kip, [o]verwrite, [k]eep both, show [d]iff (S, O, K for all remaining files): ", path)
+		answer, err := r.in.ReadString('\n')
+		if err != nil && strings.TrimSpace(answer) == "" {
+			return "", fmt.Errorf("no decision for the existing file %s, use --conflict to resolve conflicts without asking", path)
+		}
+
+		switch strings.TrimSpace(answer) {
+		case "s":
+			return ConflictSkip, nil
+		case "o":
+			return ConflictOverwrite, nil
+		case "k":
+			return ConflictKeepBoth, nil
+		case "S":
+			r.strategy = ConflictSkip
+			return r.strategy, nil
+		case "O":
+			r.strategy = ConflictOverwrite
+			return r.strategy, nil
+		case "K":
+			r.strategy = ConflictKeepBoth
+			return r.strategy, nil
+		case "d":
+			fmt.Fprint(r.out, diff.Unified(path+" (existing)", path+" (generated)", string(existing), string(generated)))
+		}
+	}
+}
+
+// applyInto copies the generated files from the staging directory into the existing target directory.
+// The sources of the patches of a component are merged into their targets instead.
+func (g *Generator) applyInto(m *manifest.Manifest, stagingDir, targetDir string, resolver *conflictResolver) ([]change, error) {
+	files, err := utils.ListFilesRecursive(stagingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	patches := make(map[string]manifest.Patch, len(m.Patches))
+	for _, p := range m.Patches {
+		// the generated name of the source file, without template suffix and DOT notation
+		source := strings.TrimSuffix(p.Source, constants.TemplateFileSuffix)
+		if strings.HasPrefix(source, constants.DotFileNotationPrefix) {
+			source = strings.Replace(source, constants.DotFileNotationPrefix, constants.DotFilePrefix, 1)
+		}
+		patches[filepath.Clean(source)] = p
+	}
+
+	changes := make([]change, 0, len(files))
+	for _, file := range files {
+		source := filepath.Join(stagingDir, file)
+		target := filepath.Join(targetDir, file)
+
+		if p, ok := patches[file]; ok {
+			patchTarget, err := utils.ContainedPath(targetDir, p.Target)
+			if err != nil {
+				return changes, fmt.Errorf("invalid patch: %v", err)
+			}
+			patched, err := applyPatch(p, source, patchTarget)
+			if err != nil {
+				return changes, err
+			}
+			changes = append(changes, change{path: p.Target, action: patched})
+			continue
+		}
+
+		if !utils.FileExists(target) {
+			if err := utils.CopyFile(source, target); err != nil {
+				return changes, err
+			}
+			changes = append(changes, change{path: file, action: actionCreated})
+			continue
+		}
+
+		generated, err := utils.ReadFile(source)
+		if err != nil {
+			return changes, err
+		}
+		existing, err := utils.ReadFile(target)
+		if err != nil {
+			return changes, fmt.Errorf("error reading the existing file %s: %v", target, err)
+		}
+		if bytes.Equal(existing, generated) {
+			changes = append(changes, change{path: file, action: actionUnchanged})
+			continue
+		}
+
+		strategy, err := resolver.resolve(file, existing, generated)
+		if err != nil {
+			return changes, err
+		}
+
+		switch strategy {
+		case ConflictSkip:
+			changes = append(changes, change{path: file, action: actionSkipped})
+		case ConflictOverwrite:
+			if err := utils.CopyFile(source, target); err != nil {
+				return changes, err
+			}
+			changes = append(changes, change{path: file, action: actionOverwritten})
+		case ConflictKeepBoth:
+			if err := utils.CopyFile(source, target+KeepBothSuffix); err != nil {
+				return changes, err
+			}
+			changes = append(changes, change{path: file + " -> " + file + KeepBothSuffix, action: actionKeptBoth})
+		default:
+			changes = append(changes, change{path: file, action: actionConflict})
+		}
+	}
+	return changes, nil
+}
+
+// applyPatch merges the generated file source into target, it returns the action for the summary.
+func applyPatch(p manifest.Patch, source, target string) (string, error) {
+	addition, err := utils.ReadFile(source)
+	if err != nil {
+		return "", err
+	}
+
+	if !utils.FileExists(target) {
+		if err := utils.CopyFile(source, target); err != nil {
+			return "", err
+		}
+		return actionCreated, nil
+	}
+
+	existing, err := utils.ReadFile(target)
+	if err != nil {
+		return "", fmt.Errorf("error reading the existing file %s: %v", target, err)
+	}
+
+	merged, err := patch.Apply(p.Type, existing, addition)
+	if err != nil {
+		return "", fmt.Errorf("error patching %s: %v", target, err)
+	}
+	if bytes.Equal(existing, merged) {
+		return actionUnchanged, nil
+	}
+
+	if err := utils.WriteFile(target, merged); err != nil {
+		return "", err
+	}
+	return actionPatched, nil
+}
+
+// printSummary prints what happened to every generated file.
+func printSummary(targetDir string, changes []change) {
+	fmt.Printf("\nSummary of the changes in %v:\n", targetDir)
+	for _, change := range changes {
+		fmt.Printf("  %-12s %s\n", change.action, change.path)
+	}
+}
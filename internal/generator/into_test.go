@@ -0,0 +1,101 @@
+package generator
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"craft/internal/manifest"
+)
+
+func TestApplyInto(t *testing.T) {
+	generated := map[string]string{
+		"README.md":  "# orders\n",
+		"Makefile":   "build:\n\tgo build ./...\n",
+		"go.mod":     "module orders\n",
+		"cmd/new.go": "package cmd\n",
+	}
+	existing := map[string]string{
+		"README.md": "# my orders\n",
+		"go.mod":    "module orders\n",
+	}
+
+	tests := []struct {
+		name       string
+		strategy   string
+		input      string
+		wantErr    string
+		wantAction string
+		wantReadme string
+		wantNew    bool
+	}{
+		{name: "skip", strategy: ConflictSkip, wantAction: actionSkipped, wantReadme: existing["README.md"]},
+		{name: "overwrite", strategy: ConflictOverwrite, wantAction: actionOverwritten, wantReadme: generated["README.md"]},
+		{name: "keep both", strategy: ConflictKeepBoth, wantAction: actionKeptBoth, wantReadme: existing["README.md"], wantNew: true},
+		{name: "ask", strategy: ConflictAsk, input: "d\no\n", wantAction: actionOverwritten, wantReadme: generated["README.md"]},
+		{name: "ask without a terminal", strategy: ConflictAsk, wantErr: "use --conflict", wantReadme: existing["README.md"]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stagingDir, targetDir := t.TempDir(), t.TempDir()
+			writeTestFiles(t, stagingDir, generated)
+			writeTestFiles(t, targetDir, existing)
+
+			resolver := &conflictResolver{strategy: tt.strategy, interactive: true, in: bufio.NewReader(strings.NewReader(tt.input)), out: io.Discard}
+			changes, err := (&Generator{}).applyInto(&manifest.Manifest{}, stagingDir, targetDir, resolver)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("applyInto() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("applyInto() error = %v", err)
+			}
+
+			actions := make(map[string]string, len(changes))
+			for _, change := range changes {
+				actions[strings.Split(change.path, " -> ")[0]] = change.action
+			}
+			if tt.wantErr == "" {
+				for path, want := range map[string]string{"README.md": tt.wantAction, "go.mod": actionUnchanged, filepath.Join("cmd", "new.go"): actionCreated} {
+					if actions[path] != want {
+						t.Errorf("%s: action = %q, want %q", path, actions[path], want)
+					}
+				}
+			}
+
+			if got := readTestFile(t, targetDir, "README.md"); got != tt.wantReadme {
+				t.Errorf("README.md = %q, want %q", got, tt.wantReadme)
+			}
+			_, err = os.Stat(filepath.Join(targetDir, "README.md"+KeepBothSuffix))
+			if hasNew := err == nil; hasNew != tt.wantNew {
+				t.Errorf("README.md%s exists = %v, want %v", KeepBothSuffix, hasNew, tt.wantNew)
+			}
+		})
+	}
+}
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readTestFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
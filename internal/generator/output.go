@@ -0,0 +1,224 @@
+package generator
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"craft/internal/constants"
+	"craft/internal/container"
+	"craft/internal/manifest"
+	"craft/internal/templating"
+	"craft/internal/utils"
+)
+
+// placeholderProjectName is the project name cached containers run for, it is replaced with the name of
+// the project in their output. The images of 'craft cache warm' are built for it as well.
+const placeholderProjectName = "craft-placeholder"
+
+// containerRun is a container of the manifest with its rendered build args and command.
+type containerRun struct {
+	manifest.Container
+	buildArgs map[string]string
+	command   []string
+	// archive is the file the output of a cached container is stored in, empty for the other containers.
+	archive string
+	// placeholder is set if the cached container runs for placeholderProjectName, which is replaced in its output.
+	placeholder bool
+}
+
+// DefaultOutputCacheDir returns the directory the output of cached containers is stored in, e.g. ~/.cache/craft/output.
+func DefaultOutputCacheDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine the cache directory: %w", err)
+	}
+	return filepath.Join(cacheDir, constants.ToolName, "output"), nil
+}
+
+// prepareContainer renders the build args and the command of the container. Cached containers are rendered
+// for placeholderProjectName, their archive is named after a hash of everything their output depends on.
+//
+// Only the name itself is replaced in the output. If the build args or the command use a form derived from
+// the name (e.g. {{ pascal .ProjectName }}), the container runs for the project name instead and the
+// name is part of the hash, so its output is only reused for projects with the same name.
+func (g *Generator) prepareContainer(m *manifest.Manifest, c manifest.Container, projectHostDir string) (containerRun, error) {
+	run := containerRun{Container: c}
+
+	var err error
+	if run.buildArgs, run.command, err = renderContainer(c, g.Context); err != nil {
+		return run, err
+	}
+	if !c.Cache {
+		return run, nil
+	}
+
+	data := g.Context
+	data.ProjectName = placeholderProjectName
+	if g.Context.ModulePath == g.Context.ProjectName {
+		data.ModulePath = placeholderProjectName
+	}
+	buildArgs, command, err := renderContainer(c, data)
+	if err != nil {
+		return run, err
+	}
+	if usesNameVerbatim(buildArgs, command, run.buildArgs, run.command, g.Context.ProjectName) {
+		run.buildArgs, run.command, run.placeholder = buildArgs, command, true
+	}
+
+	// the Dockerfile is read from the project directory, it is the one the image is built from
+	dockerfile, err := utils.ReadFile(filepath.Join(projectHostDir, c.Dockerfile))
+	if err != nil {
+		return run, fmt.Errorf("error reading the Dockerfile of %s: %v", c.Image, err)
+	}
+	template := g.templateRecord(m)
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%s\n%s\n%s\n%s\n", template.ID, template.Source, template.Revision, c.Image, dockerfile)
+	for _, name := range utils.SortedKeys(run.buildArgs) {
+		fmt.Fprintf(hash, "%s=%s\n", name, run.buildArgs[name])
+	}
+	fmt.Fprintf(hash, "%q\n", run.command)
+
+	cacheDir, err := DefaultOutputCacheDir()
+	if err != nil {
+		return run, err
+	}
+	run.archive = filepath.Join(cacheDir, fmt.Sprintf("%s-%x.tar.gz", m.Name, hash.Sum(nil)[:8]))
+	return run, nil
+}
+
+// renderContainer renders the build args and the command of the container with data.
+func renderContainer(c manifest.Container, data templating.Context) (map[string]string, []string, error) {
+	buildArgs := make(map[string]string, len(c.BuildArgs))
+	for name, value := range c.BuildArgs {
+		rendered, err := templating.RenderString(value, data)
+		if err != nil {
+			return nil, nil, err
+		}
+		buildArgs[name] = rendered
+	}
+
+	var command []string
+	for _, arg := range c.Command {
+		rendered, err := templating.RenderString(arg, data)
+		if err != nil {
+			return nil, nil, err
+		}
+		command = append(command, rendered)
+	}
+	return buildArgs, command, nil
+}
+
+// usesNameVerbatim reports whether replacing placeholderProjectName with the name in the build args and the command
+// rendered for the placeholder results in the ones rendered for the name.
+func usesNameVerbatim(buildArgs map[string]string, command []string, named map[string]string, namedCommand []string, name string) bool {
+	for arg, value := range buildArgs {
+		if strings.ReplaceAll(value, placeholderProjectName, name) != named[arg] {
+			return false
+		}
+	}
+	for i, arg := range command {
+		if strings.ReplaceAll(arg, placeholderProjectName, name) != namedCommand[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// isCached reports whether the output of the container can be restored from its archive (unless --no-cache).
+func (g *Generator) isCached(run containerRun) bool {
+	return run.archive != "" && !g.Options.NoCache && utils.FileExists(run.archive)
+}
+
+// runCached runs the cached container with an empty output directory mounted to /workspace, stores what it wrote
+// in its archive and copies it into the project.
+func (g *Generator) runCached(ctx context.Context, runtime container.Runtime, run containerRun, projectHostDir string, progress container.Progress) error {
+	outputDir, err := os.MkdirTemp(projectHostDir, ".craft-output-")
+	if err != nil {
+		return fmt.Errorf("could not create the output directory of %s: %v", run.Image, err)
+	}
+	defer os.RemoveAll(outputDir)
+
+	fmt.Printf("Running the image %s with %s\n", run.Image, runtime.Name())
+	options := container.RunOptions{
+		Image: run.Image,
+		Cmd:   run.command,
+		User:  container.UserOf(runtime),
+		Binds: []string{outputDir + ":" + containerWorkspace},
+	}
+	if err := runtime.Run(ctx, options, progress); err != nil {
+		return err
+	}
+
+	// a cache that can not be written only makes the next generation slower
+	if err := storeOutput(outputDir, run.archive); err != nil {
+		fmt.Printf("The output of %s is not cached: %v\n", run.Image, err)
+	}
+	return g.applyOutput(run, outputDir, projectHostDir)
+}
+
+// restoreOutput copies the cached output of the container into the project instead of running it.
+func (g *Generator) restoreOutput(run containerRun, projectHostDir string) error {
+	fmt.Printf("Using the cached output of %s from %s\n", run.Image, run.archive)
+
+	outputDir, err := os.MkdirTemp(projectHostDir, ".craft-output-")
+	if err != nil {
+		return fmt.Errorf("could not create the output directory of %s: %v", run.Image, err)
+	}
+	defer os.RemoveAll(outputDir)
+
+	archive, err := os.Open(run.archive)
+	if err != nil {
+		return fmt.Errorf("error opening the cached output %s: %v", run.archive, err)
+	}
+	defer archive.Close()
+
+	if err := utils.UnzipAndUntar(archive, outputDir); err != nil {
+		return fmt.Errorf("the cached output %s is broken, remove it or use --no-cache: %v", run.archive, err)
+	}
+
+	// the modification time of the archive is its last use, see 'craft cache prune'
+	now := time.Now()
+	_ = os.Chtimes(run.archive, now, now)
+
+	return g.applyOutput(run, outputDir, projectHostDir)
+}
+
+// applyOutput replaces the placeholder name with the project name in the output of a cached container
+// (if it ran for the placeholder) and copies it into the project.
+func (g *Generator) applyOutput(run containerRun, outputDir, projectHostDir string) error {
+	if run.placeholder {
+		if err := utils.ReplaceInDir(outputDir, placeholderProjectName, g.Context.ProjectName); err != nil {
+			return err
+		}
+	}
+	return utils.CopyAllEntries(outputDir, projectHostDir)
+}
+
+// storeOutput writes the output directory as tar.gz archive. It is written next to the archive first,
+// so an interrupted generation never leaves a partial archive in the cache.
+func storeOutput(outputDir, archive string) error {
+	if err := os.MkdirAll(filepath.Dir(archive), 0755); err != nil {
+		return fmt.Errorf("could not create the cache directory: %w", err)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(archive), ".output-")
+	if err != nil {
+		return fmt.Errorf("could not create the archive: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	if err := utils.TarAndZip(outputDir, file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), archive)
+}
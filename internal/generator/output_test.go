@@ -0,0 +1,117 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"craft/internal/manifest"
+	"craft/internal/templating"
+)
+
+func TestPrepareContainer(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	projectDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectDir, "build.Dockerfile"), []byte("FROM scratch\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &manifest.Manifest{Name: "test-template"}
+
+	tests := []struct {
+		name            string
+		buildArgs       map[string]string
+		command         []string
+		cache           bool
+		wantArg         string
+		wantCommand     []string
+		wantPlaceholder bool
+	}{
+		{
+			name:        "not cached",
+			buildArgs:   map[string]string{"NAME": "{{ .ProjectName }}"},
+			command:     []string{"create", "{{ .ProjectName }}"},
+			wantArg:     "orders",
+			wantCommand: []string{"create", "orders"},
+		},
+		{
+			name:            "cached with the name as it is",
+			buildArgs:       map[string]string{"NAME": "{{ .ProjectName }}"},
+			command:         []string{"create", "{{ .ProjectName }}"},
+			cache:           true,
+			wantArg:         placeholderProjectName,
+			wantCommand:     []string{"create", placeholderProjectName},
+			wantPlaceholder: true,
+		},
+		{
+			name:        "cached with a name derived in the build args",
+			buildArgs:   map[string]string{"NAME": "{{ .ProjectName }}", "CLASS": "{{ pascal .ProjectName }}"},
+			command:     []string{"create", "{{ .ProjectName }}"},
+			cache:       true,
+			wantArg:     "orders",
+			wantCommand: []string{"create", "orders"},
+		},
+		{
+			name:        "cached with a name derived in the command",
+			buildArgs:   map[string]string{"NAME": "{{ .ProjectName }}"},
+			command:     []string{"create", "{{ upper .ProjectName }}"},
+			cache:       true,
+			wantArg:     "orders",
+			wantCommand: []string{"create", "ORDERS"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Generator{Context: templating.NewContext("orders", "java", nil)}
+			c := manifest.Container{Dockerfile: "build.Dockerfile", Image: "test:latest", BuildArgs: tt.buildArgs, Command: tt.command, Cache: tt.cache}
+
+			run, err := g.prepareContainer(m, c, projectDir)
+			if err != nil {
+				t.Fatalf("prepareContainer() error = %v", err)
+			}
+			if run.buildArgs["NAME"] != tt.wantArg {
+				t.Errorf("NAME = %q, want %q", run.buildArgs["NAME"], tt.wantArg)
+			}
+			if !slices.Equal(run.command, tt.wantCommand) {
+				t.Errorf("command = %q, want %q", run.command, tt.wantCommand)
+			}
+			if run.placeholder != tt.wantPlaceholder {
+				t.Errorf("placeholder = %v, want %v", run.placeholder, tt.wantPlaceholder)
+			}
+			if (run.archive != "") != tt.cache {
+				t.Errorf("archive = %q, want one only for cached containers", run.archive)
+			}
+		})
+	}
+}
+
+// TestPrepareContainerArchive checks that only derived names make the archive depend on the project name.
+func TestPrepareContainerArchive(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	projectDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectDir, "build.Dockerfile"), []byte("FROM scratch\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &manifest.Manifest{Name: "test-template"}
+
+	archive := func(projectName, buildArg string) string {
+		t.Helper()
+		g := &Generator{Context: templating.NewContext(projectName, "java", nil)}
+		c := manifest.Container{Dockerfile: "build.Dockerfile", Image: "test:latest", BuildArgs: map[string]string{"NAME": buildArg}, Cache: true}
+		run, err := g.prepareContainer(m, c, projectDir)
+		if err != nil {
+			t.Fatalf("prepareContainer() error = %v", err)
+		}
+		return run.archive
+	}
+
+	if archive("orders", "{{ .ProjectName }}") != archive("billing", "{{ .ProjectName }}") {
+		t.Error("the archive of a container using the name as it is depends on the name")
+	}
+	if archive("orders", "{{ pascal .ProjectName }}") == archive("billing", "{{ pascal .ProjectName }}") {
+		t.Error("the archive of a container using a derived name does not depend on the name")
+	}
+}
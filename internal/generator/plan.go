@@ -0,0 +1,89 @@
+package generator
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"craft/internal/constants"
+	"craft/internal/manifest"
+	"craft/internal/utils"
+)
+
+// printPlan prints what a dry run recorded: the resulting directory tree, the rendered templates,
+// the renamed DOT files, the scripts (and docker images they build), the containers and the removed files.
+func (g *Generator) printPlan(m *manifest.Manifest, recorder *utils.Recorder, projectHostDir string, renderFiles []string, title string) {
+	relative := func(path string) string {
+		if rel, err := filepath.Rel(projectHostDir, path); err == nil {
+			return rel
+		}
+		return path
+	}
+
+	fmt.Println("Dry run, nothing was written to disk.")
+	fmt.Printf("\n%s\n", title)
+	for _, path := range recorder.Tree(projectHostDir) {
+		depth := strings.Count(strings.TrimSuffix(path, "/"), "/")
+		name := filepath.Base(path)
+		if strings.HasSuffix(path, "/") {
+			name += "/"
+		}
+		fmt.Printf("  %s%s\n", strings.Repeat("  ", depth), name)
+	}
+
+	if len(renderFiles) > 0 {
+		fmt.Println("\nRendered templates:")
+		for _, file := range renderFiles {
+			fmt.Printf("  %s -> %s\n", file, strings.TrimSuffix(file, constants.TemplateFileSuffix))
+		}
+	}
+
+	var renamed, removed []string
+	var scripts, containers []utils.Operation
+	for _, operation := range recorder.Operations() {
+		switch operation.Kind {
+		case utils.OperationRename:
+			renamed = append(renamed, fmt.Sprintf("%s -> %s", relative(operation.Path), relative(operation.Target)))
+		case utils.OperationRemove:
+			removed = append(removed, relative(operation.Path))
+		case utils.OperationRun:
+			scripts = append(scripts, operation)
+		case utils.OperationContainer:
+			containers = append(containers, operation)
+		}
+	}
+
+	if len(renamed) > 0 {
+		fmt.Println("\nRenamed:")
+		for _, rename := range renamed {
+			fmt.Printf("  %s\n", rename)
+		}
+	}
+
+	if len(scripts) > 0 {
+		fmt.Println("\nScripts:")
+		for i, script := range scripts {
+			fmt.Printf("  %s\n", strings.Join(append([]string{relative(script.Path)}, script.Args...), " "))
+			if i < len(m.Scripts) && m.Scripts[i].Image != "" {
+				fmt.Printf("    builds and runs the docker image %s\n", m.Scripts[i].Image)
+			}
+		}
+		fmt.Println("  The files created by the scripts are not known before they ran and are not listed above.")
+	}
+
+	if len(containers) > 0 {
+		fmt.Println("\nContainers:")
+		for _, c := range containers {
+			fmt.Printf("  builds the docker image %s from %s\n", c.Target, relative(c.Path))
+			fmt.Printf("    runs %s with the project directory mounted to %s\n", strings.Join(c.Args, " "), containerWorkspace)
+		}
+		fmt.Println("  The files created by the containers are not known before they ran and are not listed above.")
+	}
+
+	if len(removed) > 0 {
+		fmt.Println("\nRemoved:")
+		for _, path := range removed {
+			fmt.Printf("  %s\n", path)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"craft/internal/container"
+	"craft/internal/templating"
+	"craft/internal/utils"
+)
+
+// Warm builds the images of the containers of the template ahead of time with runtime, so the next generation
+// only has to rebuild the steps that depend on the project. The build args are rendered with the defaults
+// of the variables and placeholderProjectName, the name cached containers run for. The containers are not run.
+func (g *Generator) Warm(ctx context.Context, runtime container.Runtime) error {
+	m, err := g.LoadManifest()
+	if err != nil {
+		return err
+	}
+	if len(m.Containers) == 0 {
+		return nil
+	}
+
+	if g.Context.ProjectName == "" {
+		g.Context = templating.NewContext(placeholderProjectName, m.Language, nil)
+	}
+	g.runtime = runtime
+	if err := g.prepareContext(m); err != nil {
+		return err
+	}
+
+	renderFiles, err := g.renderFiles(m)
+	if err != nil {
+		return err
+	}
+
+	// the build context holds the copied files of the template, as it does when a generation builds the images
+	contextDir, err := utils.PrepareTempStagingDir()
+	if err != nil {
+		return err
+	}
+	defer utils.RemoveFileFromHost(contextDir)
+
+	if err := utils.CopyDirFromFSExcluding(g.TemplatesFileSystem, g.TemplatePath, contextDir, notCopied(m, renderFiles)); err != nil {
+		return fmt.Errorf("error copying files from template path: %v", err)
+	}
+
+	progress := container.PrintProgress(os.Stdout)
+	for _, c := range m.Containers {
+		run, err := g.prepareContainer(m, c, contextDir)
+		if err != nil {
+			return err
+		}
+		if err := g.buildImage(ctx, runtime, m, run, contextDir, progress); err != nil {
+			return err
+		}
+	}
+	return nil
+}
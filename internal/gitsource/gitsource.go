@@ -0,0 +1,196 @@
+package gitsource
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"craft/internal/constants"
+)
+
+// Prefix marks a template source as a git repository, e.g. git+https://github.com/org/templates.git#v1.2.0
+const Prefix = "git+"
+
+// defaultRef is used if the source does not pin a tag, branch or commit.
+const defaultRef = "HEAD"
+
+// Source is a git repository containing templates, pinned to a ref (tag, branch or commit).
+type Source struct {
+	URL string
+	Ref string
+}
+
+// String returns the source in the notation Parse accepts.
+func (s Source) String() string {
+	return Prefix + s.URL + "#" + s.Ref
+}
+
+// IsGitSource reports whether the value uses the git+<url> notation.
+func IsGitSource(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// Parse parses sources like git+file:///path/repo.git#v1.2.0, git+ssh://git@host/org/repo.git#<commit>
+// or git+https://host/org/repo.git (without a ref the default branch is used).
+func Parse(value string) (Source, error) {
+	if !IsGitSource(value) {
+		return Source{}, fmt.Errorf("invalid template source '%s', expected the format git+<url>[#<ref>]", value)
+	}
+
+	url, ref, _ := strings.Cut(strings.TrimPrefix(value, Prefix), "#")
+	if url == "" {
+		return Source{}, fmt.Errorf("invalid template source '%s': the repository url is missing", value)
+	}
+
+	validSchemes := []string{"file://", "ssh://", "https://", "http://", "git://"}
+	validScheme := false
+	for _, scheme := range validSchemes {
+		if strings.HasPrefix(url, scheme) {
+			validScheme = true
+			break
+		}
+	}
+	if !validScheme {
+		return Source{}, fmt.Errorf("invalid template source '%s': the url has to start with one of %v", value, validSchemes)
+	}
+
+	if ref == "" {
+		ref = defaultRef
+	}
+	if err := checkRef(ref); err != nil {
+		return Source{}, fmt.Errorf("invalid template source '%s': %w", value, err)
+	}
+	return Source{URL: url, Ref: ref}, nil
+}
+
+// checkRef rejects refs git would parse as an option, e.g. --upload-pack=<command>.
+func checkRef(ref string) error {
+	if strings.HasPrefix(ref, "-") {
+		return fmt.Errorf("the ref '%s' can not start with '-'", ref)
+	}
+	return nil
+}
+
+// Checkout is a local working tree of a source, checked out at the pinned ref.
+type Checkout struct {
+	Source Source
+	Dir    string
+	// Commit is the full hash of the checked out commit.
+	Commit string
+}
+
+// DefaultCacheDir returns the directory checkouts are cached in, e.g. ~/.cache/craft/templates.
+func DefaultCacheDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine the cache directory: %w", err)
+	}
+	return filepath.Join(cacheDir, constants.ToolName, "templates"), nil
+}
+
+// Fetch returns a checkout of the source. Checkouts are cached in cacheDir by url and ref,
+// so fetching the same source again works offline.
+func Fetch(source Source, cacheDir string) (*Checkout, error) {
+	checkoutDir := filepath.Join(cacheDir, cacheKey(source))
+
+	if _, err := os.Stat(checkoutDir); err == nil {
+		commit, err := resolveCommit(checkoutDir)
+		if err != nil {
+			return nil, fmt.Errorf("the cached checkout %s is broken, remove it and try again: %w", checkoutDir, err)
+		}
+		return &Checkout{Source: source, Dir: checkoutDir, Commit: commit}, nil
+	}
+
+	tmpDir, commit, err := clone(source, cacheDir)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := os.Rename(tmpDir, checkoutDir); err != nil {
+		return nil, fmt.Errorf("could not move the checkout into the cache: %w", err)
+	}
+	return &Checkout{Source: source, Dir: checkoutDir, Commit: commit}, nil
+}
+
+// Refresh fetches the source again, replacing its cached checkout. A branch or tag may point
+// to a different commit than when it was cached. The cached checkout is only replaced once the
+// new one is complete, a failing fetch (e.g. offline) keeps it.
+func Refresh(source Source, cacheDir string) (*Checkout, error) {
+	checkoutDir := filepath.Join(cacheDir, cacheKey(source))
+
+	tmpDir, commit, err := clone(source, cacheDir)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := os.RemoveAll(checkoutDir); err != nil {
+		return nil, fmt.Errorf("could not remove the cached checkout: %w", err)
+	}
+	if err := os.Rename(tmpDir, checkoutDir); err != nil {
+		return nil, fmt.Errorf("could not move the checkout into the cache: %w", err)
+	}
+	return &Checkout{Source: source, Dir: checkoutDir, Commit: commit}, nil
+}
+
+// clone checks out the source in a temporary directory inside cacheDir and returns it with the checked out commit,
+// so an interrupted clone never ends up in the cache. The caller moves or removes the directory.
+func clone(source Source, cacheDir string) (dir, commit string, err error) {
+	// not every ref comes from Parse, e.g. 'craft update --to <ref>'
+	if err := checkRef(source.Ref); err != nil {
+		return "", "", err
+	}
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return "", "", fmt.Errorf("could not create the cache directory: %w", err)
+	}
+
+	tmpDir, err := os.MkdirTemp(cacheDir, ".clone-")
+	if err != nil {
+		return "", "", fmt.Errorf("could not create a temporary directory: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(tmpDir)
+		}
+	}()
+
+	fmt.Printf("Fetching templates from %s\n", source)
+	if err := runGit("", "clone", "--quiet", "--", source.URL, tmpDir); err != nil {
+		return "", "", fmt.Errorf("could not clone %s: %w", source.URL, err)
+	}
+	if err := runGit(tmpDir, "checkout", "--quiet", "--detach", source.Ref); err != nil {
+		return "", "", fmt.Errorf("could not check out '%s' of %s: %w", source.Ref, source.URL, err)
+	}
+
+	commit, err = resolveCommit(tmpDir)
+	if err != nil {
+		return "", "", err
+	}
+	return tmpDir, commit, nil
+}
+
+func cacheKey(source Source) string {
+	hash := sha256.Sum256([]byte(source.URL + "#" + source.Ref))
+	return hex.EncodeToString(hash[:])[:16]
+}
+
+func resolveCommit(dir string) (string, error) {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("could not resolve the checked out commit: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func runGit(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
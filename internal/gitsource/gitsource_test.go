@@ -0,0 +1,215 @@
+package gitsource
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fixture is a bare repository with a working clone that commits and pushes to it.
+type fixture struct {
+	t    *testing.T
+	bare string
+	work string
+}
+
+// newFixture creates a bare repository whose first commit is tagged v1.
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	f := &fixture{t: t, bare: filepath.Join(dir, "templates.git"), work: filepath.Join(dir, "work")}
+	f.git(dir, "init", "--quiet", "--bare", f.bare)
+	f.git(dir, "clone", "--quiet", f.bare, f.work)
+	f.commit("v1")
+	f.git(f.work, "tag", "v1")
+	f.git(f.work, "push", "--quiet", "origin", "HEAD", "--tags")
+	return f
+}
+
+func (f *fixture) url() string {
+	return "file://" + f.bare
+}
+
+// commit writes the content to VERSION in the working clone, commits it and returns the commit hash.
+func (f *fixture) commit(content string) string {
+	f.t.Helper()
+	if err := os.WriteFile(filepath.Join(f.work, "VERSION"), []byte(content), 0644); err != nil {
+		f.t.Fatal(err)
+	}
+	f.git(f.work, "add", "VERSION")
+	f.git(f.work, "commit", "--quiet", "-m", content)
+	return f.git(f.work, "rev-parse", "HEAD")
+}
+
+// moveTag points the tag to a new commit with the content and pushes it.
+func (f *fixture) moveTag(tag, content string) string {
+	f.t.Helper()
+	commit := f.commit(content)
+	f.git(f.work, "tag", "--force", tag)
+	f.git(f.work, "push", "--quiet", "--force", "origin", "HEAD", "--tags")
+	return commit
+}
+
+func (f *fixture) git(dir string, args ...string) string {
+	f.t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=craft", "-c", "user.email=craft@example.com", "-c", "init.defaultBranch=main"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		f.t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func readVersion(t *testing.T, checkout *Checkout) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(checkout.Dir, "VERSION"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Source
+		wantErr string
+	}{
+		{value: "git+https://github.com/org/templates.git#v1.2.0", want: Source{URL: "https://github.com/org/templates.git", Ref: "v1.2.0"}},
+		{value: "git+ssh://git@github.com/org/templates.git#main", want: Source{URL: "ssh://git@github.com/org/templates.git", Ref: "main"}},
+		{value: "git+file:///srv/templates", want: Source{URL: "file:///srv/templates", Ref: "HEAD"}},
+		{value: "git+https://github.com/org/templates.git#", want: Source{URL: "https://github.com/org/templates.git", Ref: "HEAD"}},
+		{value: "https://github.com/org/templates.git", wantErr: "expected the format"},
+		{value: "git+#v1", wantErr: "the repository url is missing"},
+		{value: "git+ftp://example.com/templates.git", wantErr: "the url has to start with one of"},
+		{value: "git+https://github.com/org/templates.git#--upload-pack=touch", wantErr: "can not start with '-'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := Parse(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchTag(t *testing.T) {
+	f := newFixture(t)
+	want := f.git(f.work, "rev-parse", "v1")
+
+	checkout, err := Fetch(Source{URL: f.url(), Ref: "v1"}, t.TempDir())
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if checkout.Commit != want {
+		t.Errorf("Commit = %s, want %s", checkout.Commit, want)
+	}
+	if got := readVersion(t, checkout); got != "v1" {
+		t.Errorf("VERSION = %q, want %q", got, "v1")
+	}
+}
+
+func TestFetchCacheHit(t *testing.T) {
+	f := newFixture(t)
+	cacheDir := t.TempDir()
+	source := Source{URL: f.url(), Ref: "v1"}
+
+	first, err := Fetch(source, cacheDir)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+
+	// a cached checkout does not need the repository anymore
+	if err := os.RemoveAll(f.bare); err != nil {
+		t.Fatal(err)
+	}
+	second, err := Fetch(source, cacheDir)
+	if err != nil {
+		t.Fatalf("Fetch() of the cached checkout error = %v", err)
+	}
+	if second.Dir != first.Dir || second.Commit != first.Commit {
+		t.Errorf("Fetch() = %+v, want the cached %+v", second, first)
+	}
+}
+
+func TestFetchInvalidRef(t *testing.T) {
+	_, err := Fetch(Source{URL: "file:///nonexistent", Ref: "--upload-pack=touch"}, t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "can not start with '-'") {
+		t.Fatalf("Fetch() error = %v, want the ref to be rejected", err)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	f := newFixture(t)
+	cacheDir := t.TempDir()
+	source := Source{URL: f.url(), Ref: "v1"}
+
+	before, err := Fetch(source, cacheDir)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+
+	want := f.moveTag("v1", "v1-fixed")
+
+	// the cache still has the old commit until it is refreshed
+	cached, err := Fetch(source, cacheDir)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if cached.Commit != before.Commit {
+		t.Errorf("cached Commit = %s, want %s", cached.Commit, before.Commit)
+	}
+
+	after, err := Refresh(source, cacheDir)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if after.Commit != want {
+		t.Errorf("Commit = %s, want %s", after.Commit, want)
+	}
+	if after.Dir != before.Dir {
+		t.Errorf("Dir = %s, want the cached %s", after.Dir, before.Dir)
+	}
+	if got := readVersion(t, after); got != "v1-fixed" {
+		t.Errorf("VERSION = %q, want %q", got, "v1-fixed")
+	}
+}
+
+func TestRefreshKeepsCacheOnError(t *testing.T) {
+	f := newFixture(t)
+	cacheDir := t.TempDir()
+	source := Source{URL: f.url(), Ref: "v1"}
+
+	before, err := Fetch(source, cacheDir)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if err := os.RemoveAll(f.bare); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Refresh(source, cacheDir); err == nil {
+		t.Fatal("Refresh() of a missing repository succeeded")
+	}
+	if got := readVersion(t, before); got != "v1" {
+		t.Errorf("VERSION of the cached checkout = %q, want %q", got, "v1")
+	}
+}
@@ -0,0 +1,111 @@
+package cpphandler
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"craft/internal/common"
+	"craft/internal/generator"
+	"craft/internal/templating"
+	"craft/internal/utils"
+)
+
+// NewCppHandler creates CMake projects for C++ and C, the test framework is chosen with -d.
+// Both languages share the cpp template, C projects get C sources and keep the tests in C++.
+type NewCppHandler struct {
+	Dependencies        []string
+	Language            string
+	TestFramework       string
+	TemplatesFileSystem fs.FS
+	Options             common.Options
+}
+
+func (h *NewCppHandler) SetTemplatesFS(fileSystem fs.FS) {
+	h.TemplatesFileSystem = fileSystem
+}
+
+func (h *NewCppHandler) SetOptions(options common.Options) {
+	h.Options = options
+}
+
+// Supported test frameworks, the first one is the default
+var testFrameworks = []string{"gtest", "catch2"}
+
+// GetAllowedTestFrameworks exposes the supported test frameworks, the first one is the default.
+func GetAllowedTestFrameworks() []string {
+	return testFrameworks
+}
+
+func (h *NewCppHandler) evaluateDependencies() error {
+	h.TestFramework = testFrameworks[0]
+
+	selected := ""
+	for _, dependency := range h.Dependencies {
+		lowerDep := strings.ToLower(dependency)
+		if !utils.Contains(testFrameworks, lowerDep) {
+			return fmt.Errorf("unsupported dependency '%s'. Allowed dependencies are: %s",
+				dependency, strings.Join(testFrameworks, ", "))
+		}
+		if selected != "" && selected != lowerDep {
+			return fmt.Errorf("only one test framework can be used, got '%s' and '%s'", selected, lowerDep)
+		}
+		selected = lowerDep
+	}
+
+	if selected != "" {
+		h.TestFramework = selected
+	}
+	return nil
+}
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Keywords of C and C++, they cannot name a namespace or a function
+var keywords = []string{
+	"alignas", "alignof", "and", "and_eq", "asm", "auto", "bitand", "bitor", "bool", "break", "case", "catch",
+	"char", "char8_t", "char16_t", "char32_t", "class", "compl", "concept", "const", "consteval", "constexpr",
+	"constinit", "const_cast", "continue", "co_await", "co_return", "co_yield", "decltype", "default", "delete",
+	"do", "double", "dynamic_cast", "else", "enum", "explicit", "export", "extern", "false", "float", "for",
+	"friend", "goto", "if", "inline", "int", "long", "mutable", "namespace", "new", "noexcept", "not", "not_eq",
+	"nullptr", "operator", "or", "or_eq", "private", "protected", "public", "register", "reinterpret_cast",
+	"requires", "restrict", "return", "short", "signed", "sizeof", "static", "static_assert", "static_cast",
+	"struct", "switch", "template", "this", "thread_local", "throw", "true", "try", "typedef", "typeid",
+	"typename", "union", "unsigned", "using", "virtual", "void", "volatile", "wchar_t", "while", "xor", "xor_eq",
+}
+
+// identifier returns the name of the namespace, the include directory and the header guards,
+// the project name in snake case (my-lib gives my_lib).
+func identifier(projectName string) (string, error) {
+	name := templating.SnakeCase(projectName)
+	if !identifierPattern.MatchString(name) || utils.Contains(keywords, name) {
+		return "", fmt.Errorf("the project name '%s' gives the identifier '%s', which cannot name a namespace: "+
+			"it has to start with a letter and must not be a keyword", projectName, name)
+	}
+	return name, nil
+}
+
+func (h *NewCppHandler) Run(projectName string) error {
+	if err := h.evaluateDependencies(); err != nil {
+		return err
+	}
+
+	if _, err := identifier(projectName); err != nil {
+		return err
+	}
+
+	ctx := templating.NewContext(projectName, h.Language, h.Dependencies)
+	ctx.BuildTool = "cmake"
+	ctx.Framework = h.TestFramework
+
+	gen := &generator.Generator{
+		TemplatesFileSystem: h.TemplatesFileSystem,
+		TemplatePath:        filepath.Join("templates", "cpp"),
+		Context:             ctx,
+		Options:             h.Options,
+	}
+
+	return gen.Run()
+}
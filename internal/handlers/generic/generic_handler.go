@@ -0,0 +1,69 @@
+package generichandler
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"craft/internal/common"
+	"craft/internal/generator"
+	"craft/internal/templating"
+	"craft/internal/utils"
+)
+
+// NewGenericHandler creates projects for languages that have no dedicated handler.
+// Everything is driven by the manifest in templates/<language>, so adding such a template needs no Go code.
+type NewGenericHandler struct {
+	Dependencies        []string
+	Language            string
+	TemplatesFileSystem fs.FS
+	// TemplatePath overrides the default template directory templates/<language>.
+	TemplatePath string
+	Options      common.Options
+}
+
+func (h *NewGenericHandler) SetTemplatesFS(fileSystem fs.FS) {
+	h.TemplatesFileSystem = fileSystem
+}
+
+func (h *NewGenericHandler) SetOptions(options common.Options) {
+	h.Options = options
+}
+
+func (h *NewGenericHandler) Run(projectName string) error {
+	templatePath := h.TemplatePath
+	if templatePath == "" {
+		templatePath = filepath.Join("templates", h.Language)
+	}
+
+	ctx := templating.NewContext(projectName, h.Language, h.Dependencies)
+	// go templates from git repositories take --module as well
+	if h.Options.ModulePath != "" {
+		ctx.ModulePath = h.Options.ModulePath
+	}
+
+	gen := &generator.Generator{
+		TemplatesFileSystem: h.TemplatesFileSystem,
+		TemplatePath:        templatePath,
+		Context:             ctx,
+		Options:             h.Options,
+	}
+
+	m, err := gen.LoadManifest()
+	if err != nil {
+		return err
+	}
+
+	for _, dependency := range h.Dependencies {
+		if !utils.ContainsStringInsensitive(m.Dependencies, dependency) {
+			if len(m.Dependencies) == 0 {
+				return fmt.Errorf("the template '%s' does not support any dependencies", m.Name)
+			}
+			return fmt.Errorf("unsupported dependency '%s'. Allowed dependencies are: %s",
+				dependency, strings.Join(m.Dependencies, ", "))
+		}
+	}
+
+	return gen.Run()
+}
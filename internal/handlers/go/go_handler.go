@@ -2,80 +2,198 @@ package gohandler
 
 import (
 	"fmt"
+	"go/token"
 	"io/fs"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"craft/internal/common"
-	"craft/internal/constants"
+	"craft/internal/config"
+	"craft/internal/generator"
+	"craft/internal/templating"
 	"craft/internal/utils"
+
+	"golang.org/x/mod/module"
 )
 
+// NewGoHandler creates go modules. The kind of project (an application, a cli, an http service,
+// a library or a grpc service) and an optional framework are chosen with -d.
 type NewGoHandler struct {
 	Dependencies        []string
 	Language            string
+	Kind                string
+	Framework           string
 	TemplatesFileSystem fs.FS
+	Options             common.Options
 }
 
 func (h *NewGoHandler) SetTemplatesFS(fileSystem fs.FS) {
 	h.TemplatesFileSystem = fileSystem
 }
 
-var (
-	filesThatNeedProjectNameAdjustedOnce       = []string{"go.mod.template", "Makefile"}
-	filesThatNeedProjectNameAdjustedEverywhere = []string{"README.md", "docker-compose.dev.yml"}
-)
+func (h *NewGoHandler) SetOptions(options common.Options) {
+	h.Options = options
+}
 
-func (h *NewGoHandler) Run(projectName string) error {
-	var projectHostDir string
-	var err error
+// The default kind, a module with a main.go, if none is given with -d
+const defaultKind = "app"
 
-	projectHostDir, err = utils.PrepareProjectDir(projectName)
-	if err != nil {
-		fmt.Printf("Failed to get current working directory: %v\n", err)
-		return err
+// Supported combinations of kinds and frameworks
+var allowedCombinations = map[string][]string{
+	"app":  {""},
+	"cli":  {""},        // cobra
+	"http": {"", "chi"}, // net/http or chi
+	"lib":  {""},
+	"grpc": {""},
+}
+
+// GetAllowedCombinations exposes the allowed kind and framework combinations.
+func GetAllowedCombinations() map[string][]string {
+	return allowedCombinations
+}
+
+func (h *NewGoHandler) evaluateDependencies() error {
+	h.Kind = ""
+	h.Framework = ""
+
+	for _, dependency := range h.Dependencies {
+		lowerDep := strings.ToLower(dependency)
+
+		if _, isKind := allowedCombinations[lowerDep]; isKind {
+			if h.Kind != "" && h.Kind != lowerDep {
+				return fmt.Errorf("only one kind of project can be used, got '%s' and '%s'", h.Kind, lowerDep)
+			}
+			h.Kind = lowerDep
+			continue
+		}
+
+		if !utils.Contains(getAllowedFrameworks(), lowerDep) {
+			return fmt.Errorf("unsupported dependency '%s'. Allowed dependencies are: %s",
+				dependency, strings.Join(getAllowedDependencies(), ", "))
+		}
+		if h.Framework != "" && h.Framework != lowerDep {
+			return fmt.Errorf("only one framework can be used, got '%s' and '%s'", h.Framework, lowerDep)
+		}
+		h.Framework = lowerDep
 	}
-	fmt.Printf("The project directory: %v\n\n", projectHostDir)
 
-	languageTemplatePath := filepath.Join("templates", h.Language)
+	if h.Kind == "" {
+		h.Kind = defaultKindFor(h.Framework)
+	}
 
-	if err := h.copyTemplateFilesToHost(languageTemplatePath, projectHostDir); err != nil {
-		return err
+	return validateCombination(h.Kind, h.Framework)
+}
+
+// defaultKindFor returns the kind used if only a framework is given, e.g. http for chi
+func defaultKindFor(framework string) string {
+	if framework == "" {
+		return defaultKind
+	}
+	for _, kind := range utils.SortedKeys(allowedCombinations) {
+		if utils.Contains(allowedCombinations[kind], framework) {
+			return kind
+		}
 	}
+	return defaultKind
+}
 
-	if err := h.adjustProjectNames(projectHostDir, filesThatNeedProjectNameAdjustedOnce, filesThatNeedProjectNameAdjustedEverywhere, projectName); err != nil {
-		return err
+func validateCombination(kind, framework string) error {
+	validFrameworks, ok := allowedCombinations[kind]
+	if !ok {
+		return fmt.Errorf("unsupported kind '%s'. Allowed kinds are: %s",
+			kind, strings.Join(utils.SortedKeys(allowedCombinations), ", "))
 	}
 
-	dotFileCandidates, err := utils.ListFilesWithPattern(h.TemplatesFileSystem, languageTemplatePath, constants.DotFileNotationPrefix)
-	if err != nil {
-		return err
+	if utils.Contains(validFrameworks, framework) {
+		return nil
+	}
+	if len(validFrameworks) == 1 && validFrameworks[0] == "" {
+		return fmt.Errorf("unsupported combination: kind '%s' does not support a framework, got '%s'", kind, framework)
 	}
+	return fmt.Errorf("unsupported combination: kind '%s' does not support framework '%s'. Allowed frameworks for '%s' are: %s",
+		kind, framework, kind, strings.Join(validFrameworks, ", "))
+}
 
-	if err := utils.RenameFilesWithPrefix(dotFileCandidates, projectHostDir, constants.DotFileNotationPrefix, constants.DotFilePrefix); err != nil {
-		fmt.Printf("Error renaming dot files: %v\n", err)
-		return err
+// getAllowedFrameworks returns the frameworks of all kinds, sorted
+func getAllowedFrameworks() []string {
+	frameworks := make(map[string]struct{})
+	for _, validFrameworks := range allowedCombinations {
+		for _, framework := range validFrameworks {
+			if framework != "" {
+				frameworks[framework] = struct{}{}
+			}
+		}
 	}
+	return utils.SortedKeys(frameworks)
+}
 
-	templateFiles, err := utils.ListFilesWithPattern(h.TemplatesFileSystem, languageTemplatePath, constants.TemplateFileSuffix)
-	if err != nil {
+// getAllowedDependencies returns the kinds and frameworks, sorted
+func getAllowedDependencies() []string {
+	dependencies := append(utils.Keys(allowedCombinations), getAllowedFrameworks()...)
+	sort.Strings(dependencies)
+	return dependencies
+}
+
+// modulePath returns the module path of the project: the --module flag, or the project name
+// prefixed with the modulePrefix of the user configuration (e.g. gitlab.company.com/team/my-project).
+func (h *NewGoHandler) modulePath(projectName string) (string, error) {
+	modulePath := h.Options.ModulePath
+	if modulePath == "" {
+		userConfig, err := config.Load()
+		if err != nil {
+			return "", err
+		}
+
+		modulePath = projectName
+		if prefix := strings.Trim(userConfig.Go.ModulePrefix, "/"); prefix != "" {
+			modulePath = prefix + "/" + projectName
+		}
+	}
+
+	if err := module.CheckImportPath(modulePath); err != nil {
+		return "", fmt.Errorf("invalid module path '%s', use --module to set a valid one: %w", modulePath, err)
+	}
+	return modulePath, nil
+}
+
+// packageName returns the package of a library, the project name without separators (my-lib gives mylib).
+func packageName(projectName string) (string, error) {
+	name := strings.ReplaceAll(templating.SnakeCase(projectName), "_", "")
+	if !token.IsIdentifier(name) {
+		return "", fmt.Errorf("the project name '%s' gives the package name '%s', which is not a valid Go identifier: "+
+			"it has to start with a letter and must not be a keyword", projectName, name)
+	}
+	return name, nil
+}
+
+func (h *NewGoHandler) Run(projectName string) error {
+	if err := h.evaluateDependencies(); err != nil {
 		return err
 	}
 
-	if err := utils.TrimFileSuffix(templateFiles, projectHostDir, constants.TemplateFileSuffix); err != nil {
-		fmt.Printf("Error cleaning template file suffixes: %v\n", err)
+	if h.Kind == "lib" {
+		if _, err := packageName(projectName); err != nil {
+			return err
+		}
+	}
+
+	modulePath, err := h.modulePath(projectName)
+	if err != nil {
 		return err
 	}
 
-	return nil
-}
+	ctx := templating.NewContext(projectName, h.Language, h.Dependencies)
+	ctx.ModulePath = modulePath
+	ctx.Kind = h.Kind
+	ctx.Framework = h.Framework
 
-func (h *NewGoHandler) copyTemplateFilesToHost(languageTemplatePath, projectHostDir string) error {
-	if err := utils.CopyDirFromFS(h.TemplatesFileSystem, languageTemplatePath, projectHostDir); err != nil {
-		return fmt.Errorf("error copying files from template path: %v", err)
+	gen := &generator.Generator{
+		TemplatesFileSystem: h.TemplatesFileSystem,
+		TemplatePath:        filepath.Join("templates", h.Language),
+		Context:             ctx,
+		Options:             h.Options,
 	}
-	return nil
-}
 
-func (handler *NewGoHandler) adjustProjectNames(projectHostDir string, onceFiles, everywhereFiles []string, projectName string) error {
-	return common.AdjustProjectNames(projectHostDir, onceFiles, everywhereFiles, constants.ProjectNamePlaceholder, projectName)
+	return gen.Run()
 }
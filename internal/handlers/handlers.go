@@ -2,13 +2,19 @@ package handlers
 
 import (
 	"craft/internal/common"
+	cpphandler "craft/internal/handlers/cpp"
+	generichandler "craft/internal/handlers/generic"
 	gohandler "craft/internal/handlers/go"
 	javahandler "craft/internal/handlers/java"
+	pythonhandler "craft/internal/handlers/python"
 	rusthandler "craft/internal/handlers/rust"
-	"fmt"
+	typescripthandler "craft/internal/handlers/typescript"
 	"strings"
 )
 
+// GetNewHandler returns the handler for the given language.
+// Languages without a dedicated handler are generated by the generic, manifest driven handler;
+// registry.ValidateOperationAndLanguage makes sure only languages with a template get this far.
 func GetNewHandler(language string, dependencies []string) (common.NewHandler, error) {
 
 	switch strings.ToLower(language) {
@@ -29,7 +35,28 @@ func GetNewHandler(language string, dependencies []string) (common.NewHandler, e
 			Dependencies: dependencies,
 		}, nil
 
+	case "python":
+		return &pythonhandler.NewPythonHandler{
+			Language:     "python",
+			Dependencies: dependencies,
+		}, nil
+
+	case "typescript":
+		return &typescripthandler.NewTypeScriptHandler{
+			Language:     "typescript",
+			Dependencies: dependencies,
+		}, nil
+
+	case "cpp", "c":
+		return &cpphandler.NewCppHandler{
+			Language:     strings.ToLower(language),
+			Dependencies: dependencies,
+		}, nil
+
 	default:
-		return nil, fmt.Errorf("no 'new' handler found for language '%s'", language)
+		return &generichandler.NewGenericHandler{
+			Language:     strings.ToLower(language),
+			Dependencies: dependencies,
+		}, nil
 	}
 }
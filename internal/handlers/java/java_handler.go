@@ -2,13 +2,13 @@ package handlers
 
 import (
 	"craft/internal/common"
-	"craft/internal/constants"
-	"craft/internal/utils"
+	"craft/internal/generator"
+	"craft/internal/templating"
 	"fmt"
 	"io/fs"
-	"os"
 	"path/filepath"
 	"strings"
+	"unicode"
 )
 
 type NewJavaHandler struct {
@@ -16,16 +16,23 @@ type NewJavaHandler struct {
 	Language            string
 	BuildTool           string
 	Framework           string
+	MultiModule         bool
 	TemplatesFileSystem fs.FS
+	Options             common.Options
 }
 
 func (h *NewJavaHandler) SetTemplatesFS(fs fs.FS) {
 	h.TemplatesFileSystem = fs
 }
 
+func (h *NewJavaHandler) SetOptions(options common.Options) {
+	h.Options = options
+}
+
 // Supported combinations of dependencies
 var allowedCombinations = map[string][]string{
-	"maven": {"", "springboot", "quarkus"}, // Maven allows no framework, Spring Boot, or Quarkus
+	"maven":  {"", "springboot", "quarkus"}, // Maven allows no framework, Spring Boot, or Quarkus
+	"gradle": {"", "springboot", "quarkus"}, // Gradle (Kotlin DSL) allows the same frameworks
 }
 
 // GetAllowedCombinations exposes the allowed build tool and framework combinations.
@@ -33,10 +40,21 @@ func GetAllowedCombinations() map[string][]string {
 	return allowedCombinations
 }
 
+// The dependency that splits a maven project into modules (--modules), and the frameworks it can be combined with
+const multiModuleDependency = "multimodule"
+
+var multiModuleFrameworks = []string{"", "quarkus"}
+
+// GetAllowedMultiModuleFrameworks exposes the frameworks a multi-module maven project can be created with.
+func GetAllowedMultiModuleFrameworks() []string {
+	return multiModuleFrameworks
+}
+
 func (h *NewJavaHandler) evaluateDependencies() error {
 	// Default values
 	h.BuildTool = "maven"
 	h.Framework = "" // Default: No specific framework
+	h.MultiModule = false
 
 	for _, dependency := range h.Dependencies {
 		lowerDep := strings.ToLower(dependency)
@@ -54,6 +72,8 @@ func (h *NewJavaHandler) evaluateDependencies() error {
 			h.BuildTool = "gradle"
 		case "maven":
 			h.BuildTool = "maven"
+		case multiModuleDependency:
+			h.MultiModule = true
 		}
 	}
 
@@ -62,9 +82,26 @@ func (h *NewJavaHandler) evaluateDependencies() error {
 		return err
 	}
 
+	if h.MultiModule {
+		return validateMultiModule(h.BuildTool, h.Framework)
+	}
 	return nil
 }
 
+func validateMultiModule(buildTool, framework string) error {
+	if buildTool != "maven" {
+		return fmt.Errorf("unsupported combination: build tool '%s' does not support %s, only maven does", buildTool, multiModuleDependency)
+	}
+
+	for _, validFramework := range multiModuleFrameworks {
+		if framework == validFramework {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported combination: %s does not support framework '%s'. Allowed frameworks for %s are: %s",
+		multiModuleDependency, framework, multiModuleDependency, strings.Join(multiModuleFrameworks[1:], ", "))
+}
+
 func validateCombination(buildTool, framework string) error {
 	validFrameworks, ok := allowedCombinations[buildTool]
 	if !ok {
@@ -94,6 +131,7 @@ func getAllowedDependencies() []string {
 			}
 		}
 	}
+	dependencies[multiModuleDependency] = struct{}{}
 
 	// Convert map to a sorted slice
 	result := make([]string, 0, len(dependencies))
@@ -131,161 +169,180 @@ func (h *NewJavaHandler) Run(projectName string) error {
 		return fmt.Errorf("invalid configuration: Build Tool not specified")
 	}
 
-	projectHostDir, err := utils.PrepareProjectDir(projectName)
-	if err != nil {
-		return fmt.Errorf("failed to get current working directory: %v", err)
-	}
-
 	switch h.BuildTool {
 	case "maven":
-		return h.handleMavenProject(projectHostDir, projectName)
+		return h.handleMavenProject(projectName)
 	case "gradle":
-		return h.handleGradleProject()
+		return h.handleGradleProject(projectName)
 	default:
 		return fmt.Errorf("unsupported build tool '%s'", h.BuildTool)
 	}
 }
 
-func (h *NewJavaHandler) handleMavenProject(projectHostDir, projectName string) error {
+func (h *NewJavaHandler) handleMavenProject(projectName string) error {
+	if h.MultiModule {
+		return h.generateProject(projectName, multiModuleDependency)
+	}
+
 	switch h.Framework {
 	case "":
 		// Default case: No specific framework
-		return h.setupDefaultMavenProject(projectHostDir, projectName)
+		return h.generateProject(projectName, "default")
 	case "quarkus":
-		return h.setupQuarkusMavenProject(projectHostDir, projectName)
+		return h.generateProject(projectName, "quarkus")
 	case "springboot":
-		return fmt.Errorf("setting up a Spring Boot project with Maven is not yet implemented")
+		return h.generateProject(projectName, "springboot")
 	default:
 		return fmt.Errorf("unsupported framework '%s' for Maven", h.Framework)
 	}
 }
 
-func (h *NewJavaHandler) handleGradleProject() error {
+func (h *NewJavaHandler) handleGradleProject(projectName string) error {
 	switch h.Framework {
 	case "":
 		// Default case: No specific framework
-		return fmt.Errorf("setting up a plain Java project for Gradle is not yet implemented")
+		return h.generateProject(projectName, "default")
 	case "quarkus":
-		return fmt.Errorf("setting up a Quarkus project with Gradle is not yet implemented")
+		return h.generateProject(projectName, "quarkus")
+	case "springboot":
+		return h.generateProject(projectName, "springboot")
 	default:
 		return fmt.Errorf("unsupported framework '%s' for Gradle", h.Framework)
 	}
 }
 
-func (h *NewJavaHandler) setupQuarkusMavenProject(projectHostDir, projectName string) error {
-	filesThatNeedProjectNameAdjustedOnce := []string{"Makefile"}
-	filesThatNeedProjectNameAdjustedEverywhere := []string{"partialREADME.md", "docker-compose.dev.yml"}
-	filesThatNeedToBeRemoved := []string{"build.Dockerfile", "create_java_project.sh", "partialREADME.md"}
-	filesThatNeedToBeRemovedInTheJavaFolder := []string{".dockerignore"} // .dockerignore is added since quarkus creates there own .dockerignore, which has to be removed before ours is copied over (we want our in the final project)
-
-	javaProjectPath := filepath.Join(projectHostDir, projectName)
+// The template variables holding the maven coordinates, the java package and the modules (--group-id, --package and --modules)
+const (
+	groupIdVariable = "GroupId"
+	packageVariable = "Package"
+	modulesVariable = "Modules"
+)
 
-	languageTemplatePath := filepath.Join("templates", h.Language, h.BuildTool, "quarkus")
-	if err := h.copyTemplateFilesToHost(languageTemplatePath, projectHostDir); err != nil {
-		return err
-	}
-	scriptPath := filepath.Join(projectHostDir, "create_java_project.sh")
-	if err := h.executeProjectSetupScript(scriptPath, projectName, projectHostDir); err != nil {
-		return err
-	}
+// Reserved words of java, they can't be used as a part of a package name
+var javaKeywords = []string{
+	"abstract", "assert", "boolean", "break", "byte", "case", "catch", "char", "class", "const",
+	"continue", "default", "do", "double", "else", "enum", "extends", "final", "finally", "float",
+	"for", "goto", "if", "implements", "import", "instanceof", "int", "interface", "long", "native",
+	"new", "package", "private", "protected", "public", "return", "short", "static", "strictfp", "super",
+	"switch", "synchronized", "this", "throw", "throws", "transient", "try", "void", "volatile", "while",
+	"true", "false", "null", "_",
+}
 
-	if err := h.cleanupFiles(javaProjectPath, filesThatNeedToBeRemovedInTheJavaFolder); err != nil {
-		return err
+// resolveVariables validates the groupId and package given with --group-id, --package or --set.
+// Without a package the groupId is used as the package, like the maven archetypes do.
+func (h *NewJavaHandler) resolveVariables() (map[string]string, error) {
+	variables := make(map[string]string, len(h.Options.Variables)+1)
+	for name, value := range h.Options.Variables {
+		variables[name] = value
 	}
 
-	dotFileCandidates, err := utils.ListFilesWithPattern(h.TemplatesFileSystem, languageTemplatePath, constants.DotFileNotationPrefix)
-	if err != nil {
-		return err
+	if _, ok := variables[packageVariable]; !ok {
+		if groupId, ok := variables[groupIdVariable]; ok {
+			variables[packageVariable] = groupId
+		}
 	}
 
-	if err := utils.RenameFilesWithPrefix(dotFileCandidates, projectHostDir, constants.DotFileNotationPrefix, constants.DotFilePrefix); err != nil {
-		fmt.Printf("Error renaming dot files: %v\n", err)
-		return err
+	if value, ok := variables[groupIdVariable]; ok {
+		if err := validateQualifiedName(value); err != nil {
+			return nil, fmt.Errorf("invalid groupId '%s': %v", value, err)
+		}
 	}
-
-	if err := utils.CopyAllOnePathUpAndRemoveDir(javaProjectPath); err != nil {
-		return err
+	if value, ok := variables[packageVariable]; ok {
+		if err := validateQualifiedName(value); err != nil {
+			return nil, fmt.Errorf("invalid package '%s': %v", value, err)
+		}
 	}
 
-	if err := h.adjustProjectNames(projectHostDir, filesThatNeedProjectNameAdjustedOnce, filesThatNeedProjectNameAdjustedEverywhere, projectName); err != nil {
-		return err
+	if value, ok := variables[modulesVariable]; ok {
+		if !h.MultiModule {
+			return nil, fmt.Errorf("--modules can only be used with -d %s", multiModuleDependency)
+		}
+		modules, err := normalizeModules(value)
+		if err != nil {
+			return nil, err
+		}
+		variables[modulesVariable] = modules
 	}
+	return variables, nil
+}
 
-	ourReadmePath := filepath.Join(projectHostDir, "partialREADME.md")
-	data, err := os.ReadFile(ourReadmePath)
-	if err != nil {
-		return nil
+// normalizeModules validates the comma separated module names and returns them without spaces.
+// Every module is a part of the java package of its sources, the last one is the application.
+func normalizeModules(value string) (string, error) {
+	var modules []string
+	for _, module := range strings.Split(value, ",") {
+		module = strings.TrimSpace(module)
+		if module == "" {
+			continue
+		}
+		if strings.Contains(module, ".") || module != strings.ToLower(module) {
+			return "", fmt.Errorf("invalid module '%s': it must be lower case and must not contain a dot", module)
+		}
+		if err := validateQualifiedName(module); err != nil {
+			return "", fmt.Errorf("invalid module '%s': %v", module, err)
+		}
+		for _, existing := range modules {
+			if existing == module {
+				return "", fmt.Errorf("the module '%s' is listed twice", module)
+			}
+		}
+		modules = append(modules, module)
 	}
-	theirReadmePath := filepath.Join(projectHostDir, "README.md")
-	quarkusPlaceholder := "# " + projectName
-	err = utils.ChangeWordInFile(theirReadmePath, quarkusPlaceholder, string(data), false)
 
-	if err := h.cleanupFiles(projectHostDir, filesThatNeedToBeRemoved); err != nil {
-		return err
+	if len(modules) < 2 {
+		return "", fmt.Errorf("at least two modules are needed, a library and the application, got '%s'", value)
 	}
-
-	fmt.Printf("A docker image named 'quarkus-project-generator:latest' is still on your host and isn't cleaned up (can be done by running: 'docker image rm quarkus-project-generator:latest')\n Not removing it will speed up the next creation of a java quarkus project immensely")
-	return nil
+	return strings.Join(modules, ","), nil
 }
 
-func (h *NewJavaHandler) setupDefaultMavenProject(projectHostDir, projectName string) error {
-	filesThatNeedProjectNameAdjustedOnce := []string{"Makefile"}
-	filesThatNeedProjectNameAdjustedEverywhere := []string{"README.md", "docker-compose.dev.yml"}
-	filesThatNeedToBeRemoved := []string{"build.Dockerfile", "create_java_project.sh"}
-
-	javaProjectPath := filepath.Join(projectHostDir, projectName)
-
-	languageTemplatePath := filepath.Join("templates", h.Language, h.BuildTool, "default")
-
-	if err := h.copyTemplateFilesToHost(languageTemplatePath, projectHostDir); err != nil {
-		return err
-	}
-
-	scriptPath := filepath.Join(projectHostDir, "create_java_project.sh")
-	if err := h.executeProjectSetupScript(scriptPath, projectName, projectHostDir); err != nil {
-		return err
+// validateQualifiedName checks that every dot separated part of name is a legal java identifier (e.g. com.example.app)
+func validateQualifiedName(name string) error {
+	if name == "" {
+		return fmt.Errorf("it must not be empty")
 	}
 
-	if err := utils.CopyAllOnePathUpAndRemoveDir(javaProjectPath); err != nil {
-		return err
+	for _, part := range strings.Split(name, ".") {
+		if part == "" {
+			return fmt.Errorf("it contains an empty part")
+		}
+		for i, r := range part {
+			if r == '_' || r == '$' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r)) {
+				continue
+			}
+			return fmt.Errorf("'%s' is not a legal java identifier", part)
+		}
+		for _, keyword := range javaKeywords {
+			if part == keyword {
+				return fmt.Errorf("'%s' is a reserved word of java", part)
+			}
+		}
 	}
+	return nil
+}
 
-	dotFileCandidates, err := utils.ListFilesWithPattern(h.TemplatesFileSystem, languageTemplatePath, constants.DotFileNotationPrefix)
+// generateProject runs the template found in templates/java/<build tool>/<variant>.
+// The multimodule variant covers all its frameworks with one template.
+func (h *NewJavaHandler) generateProject(projectName, variant string) error {
+	variables, err := h.resolveVariables()
 	if err != nil {
 		return err
 	}
-	if err := utils.RenameFilesWithPrefix(dotFileCandidates, projectHostDir, constants.DotFileNotationPrefix, constants.DotFilePrefix); err != nil {
-		fmt.Printf("Error renaming dot files: %v\n", err)
-		return err
-	}
-
-	if err := h.adjustProjectNames(projectHostDir, filesThatNeedProjectNameAdjustedOnce, filesThatNeedProjectNameAdjustedEverywhere, projectName); err != nil {
-		return err
-	}
-
-	if err := h.cleanupFiles(projectHostDir, filesThatNeedToBeRemoved); err != nil {
-		return err
+	options := h.Options
+	options.Variables = variables
+
+	ctx := templating.NewContext(projectName, h.Language, h.Dependencies)
+	ctx.BuildTool = h.BuildTool
+	ctx.Framework = h.Framework
+	if h.MultiModule {
+		ctx.Kind = multiModuleDependency
 	}
 
-	fmt.Printf("A docker image named 'maven-project-generator:latest' is still on your host and isn't cleaned up (can be done by running: 'docker image rm maven-project-generator:latest')\n Not removing it will speed up the next creation of a java maven project immensely")
-	return nil
-}
-
-func (h *NewJavaHandler) copyTemplateFilesToHost(languageTemplatePath, projectHostDir string) error {
-	if err := utils.CopyDirFromFS(h.TemplatesFileSystem, languageTemplatePath, projectHostDir); err != nil {
-		return fmt.Errorf("error copying files from template path: %v", err)
+	gen := &generator.Generator{
+		TemplatesFileSystem: h.TemplatesFileSystem,
+		TemplatePath:        filepath.Join("templates", h.Language, h.BuildTool, variant),
+		Context:             ctx,
+		Options:             options,
 	}
-	return nil
-}
-
-func (h *NewJavaHandler) executeProjectSetupScript(scriptPath, projectName, projectHostDir string) error {
-	return utils.ExecuteScript(scriptPath, projectHostDir, projectName)
-}
-
-func (h *NewJavaHandler) cleanupFiles(projectHostDir string, files []string) error {
-	return common.CleanupFiles(projectHostDir, files)
-}
 
-func (h *NewJavaHandler) adjustProjectNames(projectHostDir string, onceFiles, everywhereFiles []string, projectName string) error {
-	return common.AdjustProjectNames(projectHostDir, onceFiles, everywhereFiles, constants.ProjectNamePlaceholder, projectName)
+	return gen.Run()
 }
@@ -0,0 +1,102 @@
+package pythonhandler
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"craft/internal/common"
+	"craft/internal/generator"
+	"craft/internal/templating"
+	"craft/internal/utils"
+)
+
+// NewPythonHandler creates python packages in src layout, the package manager is chosen with -d.
+type NewPythonHandler struct {
+	Dependencies        []string
+	Language            string
+	PackageManager      string
+	TemplatesFileSystem fs.FS
+	Options             common.Options
+}
+
+func (h *NewPythonHandler) SetTemplatesFS(fileSystem fs.FS) {
+	h.TemplatesFileSystem = fileSystem
+}
+
+func (h *NewPythonHandler) SetOptions(options common.Options) {
+	h.Options = options
+}
+
+// Supported package managers, the first one is the default
+var packageManagers = []string{"uv", "poetry", "pip"}
+
+// GetAllowedPackageManagers exposes the supported package managers, the first one is the default.
+func GetAllowedPackageManagers() []string {
+	return packageManagers
+}
+
+func (h *NewPythonHandler) evaluateDependencies() error {
+	h.PackageManager = packageManagers[0]
+
+	selected := ""
+	for _, dependency := range h.Dependencies {
+		lowerDep := strings.ToLower(dependency)
+		if !utils.Contains(packageManagers, lowerDep) {
+			return fmt.Errorf("unsupported dependency '%s'. Allowed dependencies are: %s",
+				dependency, strings.Join(packageManagers, ", "))
+		}
+		if selected != "" && selected != lowerDep {
+			return fmt.Errorf("only one package manager can be used, got '%s' and '%s'", selected, lowerDep)
+		}
+		selected = lowerDep
+	}
+
+	if selected != "" {
+		h.PackageManager = selected
+	}
+	return nil
+}
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Keywords of python, they cannot be imported
+var keywords = []string{
+	"false", "none", "true", "and", "as", "assert", "async", "await", "break", "class", "continue", "def", "del",
+	"elif", "else", "except", "finally", "for", "from", "global", "if", "import", "in", "is", "lambda",
+	"nonlocal", "not", "or", "pass", "raise", "return", "try", "while", "with", "yield",
+}
+
+// importName returns the package of the project, the project name in snake case (my-app gives my_app).
+func importName(projectName string) (string, error) {
+	name := templating.SnakeCase(projectName)
+	if !identifierPattern.MatchString(name) || utils.Contains(keywords, name) {
+		return "", fmt.Errorf("the project name '%s' gives the package name '%s', which cannot be imported: "+
+			"it has to start with a letter and must not be a keyword", projectName, name)
+	}
+	return name, nil
+}
+
+func (h *NewPythonHandler) Run(projectName string) error {
+	if err := h.evaluateDependencies(); err != nil {
+		return err
+	}
+
+	if _, err := importName(projectName); err != nil {
+		return err
+	}
+
+	ctx := templating.NewContext(projectName, h.Language, h.Dependencies)
+	ctx.BuildTool = h.PackageManager
+
+	gen := &generator.Generator{
+		TemplatesFileSystem: h.TemplatesFileSystem,
+		TemplatePath:        filepath.Join("templates", h.Language),
+		Context:             ctx,
+		Options:             h.Options,
+	}
+
+	return gen.Run()
+}
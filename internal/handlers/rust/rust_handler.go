@@ -1,100 +1,147 @@
 package rusthandler
 
 import (
-	"craft/internal/common"
-	"craft/internal/constants"
-	"craft/internal/utils"
 	"fmt"
 	"io/fs"
 	"path/filepath"
+	"sort"
+	"strings"
+
+	"craft/internal/common"
+	"craft/internal/generator"
+	"craft/internal/templating"
+	"craft/internal/utils"
 )
 
+// NewRustHandler creates cargo projects: a binary crate, a library crate or a workspace,
+// optionally with the preset stacks axum, tokio and clap, all chosen with -d.
 type NewRustHandler struct {
 	Dependencies        []string
 	Language            string
+	Kind                string
+	Stacks              []string
 	TemplatesFileSystem fs.FS
+	Options             common.Options
 }
 
 func (h *NewRustHandler) SetTemplatesFS(fileSystem fs.FS) {
 	h.TemplatesFileSystem = fileSystem
 }
 
-var (
-	filesThatNeedProjectNameAdjustedOnce       = []string{"Makefile"}
-	filesThatNeedProjectNameAdjustedEverywhere = []string{"README.md", "docker-compose.dev.yml"}
-	filesThatNeedToBeRemoved                   = []string{"build.Dockerfile", "create_rust_project.sh"}
-	filesThatNeedToBeRemovedInTheRustFolder    = []string{".gitignore", ".git"} // .gitignore & .git since cargo creates there own .gitignore and .git directory (their stuff has to be removed before ours is copied over (we want ours in the final project))
-)
-
-func (h *NewRustHandler) Run(projectName string) error {
+func (h *NewRustHandler) SetOptions(options common.Options) {
+	h.Options = options
+}
 
-	var projectHostDir string
-	var err error
+// The default kind, if none is given with -d
+const defaultKind = "bin"
 
-	projectHostDir, err = utils.PrepareProjectDir(projectName)
-	rustProjectPath := filepath.Join(projectHostDir, projectName)
+// Supported kinds of crates and the preset stacks they can be combined with
+var allowedCombinations = map[string][]string{
+	"bin":       {"axum", "tokio", "clap"},
+	"lib":       {"tokio"},
+	"workspace": {"axum", "tokio", "clap"}, // the stacks are used by the binary crate
+}
 
-	if err != nil {
-		fmt.Printf("Failed to get current working directory: %v\n", err)
-		return err
-	}
-	fmt.Printf("The project directory: %v\n\n", projectHostDir)
+// Stacks that bring other stacks with them
+var impliedStacks = map[string][]string{
+	"axum": {"tokio"},
+}
 
-	languageTemplatePath := filepath.Join("templates", h.Language)
+// GetAllowedCombinations exposes the allowed kind and stack combinations.
+func GetAllowedCombinations() map[string][]string {
+	return allowedCombinations
+}
 
-	if err := h.copyTemplateFilesToHost(languageTemplatePath, projectHostDir); err != nil {
-		return err
+func (h *NewRustHandler) evaluateDependencies() error {
+	h.Kind = ""
+	h.Stacks = nil
+
+	for _, dependency := range h.Dependencies {
+		lowerDep := strings.ToLower(dependency)
+
+		if _, isKind := allowedCombinations[lowerDep]; isKind {
+			if h.Kind != "" && h.Kind != lowerDep {
+				return fmt.Errorf("only one kind of crate can be used, got '%s' and '%s'", h.Kind, lowerDep)
+			}
+			h.Kind = lowerDep
+			continue
+		}
+
+		if !utils.Contains(getAllowedStacks(), lowerDep) {
+			return fmt.Errorf("unsupported dependency '%s'. Allowed dependencies are: %s",
+				dependency, strings.Join(getAllowedDependencies(), ", "))
+		}
+		h.addStack(lowerDep)
 	}
 
-	scriptPath := filepath.Join(projectHostDir, "create_rust_project.sh")
-	if err := h.executeProjectSetupScript(scriptPath, projectName, projectHostDir); err != nil {
-		return err
+	if h.Kind == "" {
+		h.Kind = defaultKind
 	}
+	sort.Strings(h.Stacks)
 
-	if err := h.cleanupFiles(rustProjectPath, filesThatNeedToBeRemovedInTheRustFolder); err != nil {
-		return err
-	}
+	return validateCombination(h.Kind, h.Stacks)
+}
 
-	if err := utils.CopyAllOnePathUpAndRemoveDir(rustProjectPath); err != nil {
-		return err
+// addStack adds the stack and the stacks it implies, each only once
+func (h *NewRustHandler) addStack(stack string) {
+	if utils.Contains(h.Stacks, stack) {
+		return
 	}
-
-	if err := h.adjustProjectNames(projectHostDir, filesThatNeedProjectNameAdjustedOnce, filesThatNeedProjectNameAdjustedEverywhere, projectName); err != nil {
-		return err
+	h.Stacks = append(h.Stacks, stack)
+	for _, implied := range impliedStacks[stack] {
+		h.addStack(implied)
 	}
+}
 
-	dotFileCandidates, err := utils.ListFilesWithPattern(h.TemplatesFileSystem, languageTemplatePath, constants.DotFileNotationPrefix)
-	if err != nil {
-		return err
-	}
-	if err := utils.RenameFilesWithPrefix(dotFileCandidates, projectHostDir, constants.DotFileNotationPrefix, constants.DotFilePrefix); err != nil {
-		fmt.Printf("Error renaming dot files: %v\n", err)
-		return err
+func validateCombination(kind string, stacks []string) error {
+	validStacks, ok := allowedCombinations[kind]
+	if !ok {
+		return fmt.Errorf("unsupported kind '%s'. Allowed kinds are: %s",
+			kind, strings.Join(utils.SortedKeys(allowedCombinations), ", "))
 	}
 
-	if err := h.cleanupFiles(projectHostDir, filesThatNeedToBeRemoved); err != nil {
-		return err
+	for _, stack := range stacks {
+		if !utils.Contains(validStacks, stack) {
+			return fmt.Errorf("unsupported combination: kind '%s' does not support stack '%s'. Allowed stacks for '%s' are: %s",
+				kind, stack, kind, strings.Join(validStacks, ", "))
+		}
 	}
-
-	fmt.Printf("A docker image named 'rust-project-generator:latest' is still on your host and isn't cleaned up (can be done by running: 'docker image rm rust-project-generator:latest')\n Not removing it will speed up the next creation of a rust project immensely")
 	return nil
 }
 
-func (h *NewRustHandler) copyTemplateFilesToHost(languageTemplatePath, projectHostDir string) error {
-	if err := utils.CopyDirFromFS(h.TemplatesFileSystem, languageTemplatePath, projectHostDir); err != nil {
-		return fmt.Errorf("error copying files from template path: %v", err)
+// getAllowedStacks returns the stacks of all kinds, sorted
+func getAllowedStacks() []string {
+	stacks := make(map[string]struct{})
+	for _, validStacks := range allowedCombinations {
+		for _, stack := range validStacks {
+			stacks[stack] = struct{}{}
+		}
 	}
-	return nil
+	return utils.SortedKeys(stacks)
 }
 
-func (h *NewRustHandler) executeProjectSetupScript(scriptPath, projectName, projectHostDir string) error {
-	return utils.ExecuteScript(scriptPath, projectHostDir, projectName)
+// getAllowedDependencies returns the kinds and stacks, sorted
+func getAllowedDependencies() []string {
+	dependencies := append(utils.Keys(allowedCombinations), getAllowedStacks()...)
+	sort.Strings(dependencies)
+	return dependencies
 }
 
-func (h *NewRustHandler) adjustProjectNames(projectHostDir string, onceFiles, everywhereFiles []string, projectName string) error {
-	return common.AdjustProjectNames(projectHostDir, onceFiles, everywhereFiles, constants.ProjectNamePlaceholder, projectName)
-}
+func (h *NewRustHandler) Run(projectName string) error {
+	if err := h.evaluateDependencies(); err != nil {
+		return err
+	}
+
+	ctx := templating.NewContext(projectName, h.Language, h.Dependencies)
+	ctx.Kind = h.Kind
+	ctx.Stacks = h.Stacks
+
+	gen := &generator.Generator{
+		TemplatesFileSystem: h.TemplatesFileSystem,
+		TemplatePath:        filepath.Join("templates", h.Language),
+		Context:             ctx,
+		Options:             h.Options,
+	}
 
-func (h *NewRustHandler) cleanupFiles(projectHostDir string, files []string) error {
-	return common.CleanupFiles(projectHostDir, files)
+	return gen.Run()
 }
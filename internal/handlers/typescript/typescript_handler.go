@@ -0,0 +1,147 @@
+package typescripthandler
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"craft/internal/common"
+	"craft/internal/generator"
+	"craft/internal/templating"
+	"craft/internal/utils"
+)
+
+// NewTypeScriptHandler creates TypeScript projects for Node.js.
+// The package manager and an optional framework (or a library instead of an application) are chosen with -d.
+type NewTypeScriptHandler struct {
+	Dependencies        []string
+	Language            string
+	PackageManager      string
+	Framework           string
+	TemplatesFileSystem fs.FS
+	Options             common.Options
+}
+
+func (h *NewTypeScriptHandler) SetTemplatesFS(fileSystem fs.FS) {
+	h.TemplatesFileSystem = fileSystem
+}
+
+func (h *NewTypeScriptHandler) SetOptions(options common.Options) {
+	h.Options = options
+}
+
+// The default package manager, if none is given with -d
+const defaultPackageManager = "npm"
+
+// Supported combinations of package managers and frameworks, "" is a plain node application and "lib" a library
+var allowedCombinations = map[string][]string{
+	"npm":  {"", "express", "fastify", "lib"},
+	"pnpm": {"", "express", "fastify", "lib"},
+	"yarn": {"", "express", "fastify", "lib"},
+}
+
+// Alternative names of frameworks, e.g. -d library for -d lib
+var frameworkAliases = map[string]string{
+	"library": "lib",
+}
+
+// GetAllowedCombinations exposes the allowed package manager and framework combinations.
+func GetAllowedCombinations() map[string][]string {
+	return allowedCombinations
+}
+
+func (h *NewTypeScriptHandler) evaluateDependencies() error {
+	h.PackageManager = ""
+	h.Framework = ""
+
+	for _, dependency := range h.Dependencies {
+		lowerDep := strings.ToLower(dependency)
+		if framework, isAlias := frameworkAliases[lowerDep]; isAlias {
+			lowerDep = framework
+		}
+
+		if _, isPackageManager := allowedCombinations[lowerDep]; isPackageManager {
+			if h.PackageManager != "" && h.PackageManager != lowerDep {
+				return fmt.Errorf("only one package manager can be used, got '%s' and '%s'", h.PackageManager, lowerDep)
+			}
+			h.PackageManager = lowerDep
+			continue
+		}
+
+		if !utils.Contains(getAllowedFrameworks(), lowerDep) {
+			return fmt.Errorf("unsupported dependency '%s'. Allowed dependencies are: %s",
+				dependency, strings.Join(getAllowedDependencies(), ", "))
+		}
+		if h.Framework != "" && h.Framework != lowerDep {
+			return fmt.Errorf("only one framework can be used, got '%s' and '%s'", h.Framework, lowerDep)
+		}
+		h.Framework = lowerDep
+	}
+
+	if h.PackageManager == "" {
+		h.PackageManager = defaultPackageManager
+	}
+
+	return validateCombination(h.PackageManager, h.Framework)
+}
+
+func validateCombination(packageManager, framework string) error {
+	validFrameworks, ok := allowedCombinations[packageManager]
+	if !ok {
+		return fmt.Errorf("unsupported package manager '%s'. Allowed package managers are: %s",
+			packageManager, strings.Join(utils.SortedKeys(allowedCombinations), ", "))
+	}
+
+	if utils.Contains(validFrameworks, framework) {
+		return nil
+	}
+	return fmt.Errorf("unsupported combination: package manager '%s' does not support framework '%s'. Allowed frameworks for '%s' are: %s",
+		packageManager, framework, packageManager, strings.Join(validFrameworks, ", "))
+}
+
+// getAllowedFrameworks returns the frameworks of all package managers, sorted
+func getAllowedFrameworks() []string {
+	frameworks := make(map[string]struct{})
+	for _, validFrameworks := range allowedCombinations {
+		for _, framework := range validFrameworks {
+			if framework != "" {
+				frameworks[framework] = struct{}{}
+			}
+		}
+	}
+
+	result := make([]string, 0, len(frameworks))
+	for framework := range frameworks {
+		result = append(result, framework)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// getAllowedDependencies returns the package managers and frameworks, sorted
+func getAllowedDependencies() []string {
+	dependencies := append(utils.Keys(allowedCombinations), getAllowedFrameworks()...)
+	sort.Strings(dependencies)
+	return dependencies
+}
+
+func (h *NewTypeScriptHandler) Run(projectName string) error {
+	if err := h.evaluateDependencies(); err != nil {
+		return err
+	}
+
+	ctx := templating.NewContext(projectName, h.Language, h.Dependencies)
+	ctx.BuildTool = h.PackageManager
+	ctx.Framework = h.Framework
+
+	gen := &generator.Generator{
+		TemplatesFileSystem: h.TemplatesFileSystem,
+		TemplatePath:        filepath.Join("templates", h.Language),
+		Context:             ctx,
+		Options:             h.Options,
+	}
+
+	return gen.Run()
+}
@@ -0,0 +1,410 @@
+package manifest
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"craft/internal/utils"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the name of the manifest file every template directory has to contain.
+const FileName = "template.yaml"
+
+// KindComponent marks a template that is added to an existing project with 'craft add' instead of creating one.
+const KindComponent = "component"
+
+// ComponentsDir is the directory inside the templates directory that holds the components.
+const ComponentsDir = "components"
+
+// AnyLanguage is the language of components that can be added to projects of every language.
+const AnyLanguage = "any"
+
+// Kinds of patches.
+const (
+	PatchYAML     = "yaml"
+	PatchMakefile = "makefile"
+	PatchAppend   = "append"
+)
+
+// Manifest declares how a template directory is turned into a project.
+// All string values except Name, Language, Description and Dependencies are rendered
+// with the template context before they are used, e.g. hoist: "{{ .ProjectName }}".
+type Manifest struct {
+	Name string `yaml:"name"`
+	// Kind is empty for project templates and KindComponent for components.
+	Kind         string            `yaml:"kind"`
+	Language     string            `yaml:"language"`
+	Description  string            `yaml:"description"`
+	Dependencies []string          `yaml:"dependencies"`
+	Versions     map[string]string `yaml:"versions"`
+	Variables    []Variable        `yaml:"variables"`
+	// Render lists the files (relative to the template directory) that are rendered with text/template.
+	// If empty, every file ending with constants.TemplateFileSuffix is rendered.
+	Render []string `yaml:"render"`
+	// Repeats render a directory of the template once for every item of a list, e.g. the modules of a project.
+	Repeats []Repeat `yaml:"repeats"`
+	Scripts []Script `yaml:"scripts"`
+	// Containers build generator images and run them on the project directory, after the scripts.
+	Containers []Container `yaml:"containers"`
+	// Skeleton is rendered instead of running the Containers if no container runtime is used
+	// (--no-docker or none is available), it creates the same files the containers would create.
+	Skeleton *Skeleton `yaml:"skeleton"`
+	// Hoist is a directory created by the scripts or containers whose content is moved up into the project root.
+	Hoist string `yaml:"hoist"`
+	// Prune lists files that are removed inside the Hoist directory before it is moved up.
+	Prune  []string `yaml:"prune"`
+	Merges []Merge  `yaml:"merges"`
+	// Delete lists files that are removed at the end, entries that render to an empty string are ignored.
+	Delete []string `yaml:"delete"`
+	// Moves relocate files or directories after everything else, e.g. sources into the directory of a java package.
+	// Moves whose source renders to an empty string are ignored.
+	Moves    []Move   `yaml:"moves"`
+	Messages []string `yaml:"messages"`
+	// Patches change existing files of the project a component is added to.
+	Patches []Patch `yaml:"patches"`
+}
+
+// Variable is a value the template can reference with {{ .Variables.<Name> }}.
+type Variable struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Default     string `yaml:"default"`
+	Required    bool   `yaml:"required"`
+	// Choices restricts the value to one of the listed values.
+	Choices []string `yaml:"choices"`
+	// List marks a comma separated value, every item has to be one of the Choices then.
+	List bool `yaml:"list"`
+}
+
+// Script is a setup script (e.g. setup.sh) that is executed inside the project directory.
+type Script struct {
+	Path string   `yaml:"path"`
+	Args []string `yaml:"args"`
+	// Image is the docker image the script builds, it is only used for informational output.
+	Image string `yaml:"image"`
+}
+
+// Container builds the image Image from Dockerfile, with the project directory as build context, and runs
+// Command in it with the project directory mounted to /workspace. The container runs as the user that owns
+// the files on the host (see container.Runtime), the build args UID and GID are always set to that user.
+// BuildArgs and Command are rendered.
+type Container struct {
+	Dockerfile string            `yaml:"dockerfile"`
+	Image      string            `yaml:"image"`
+	BuildArgs  map[string]string `yaml:"buildArgs"`
+	Command    []string          `yaml:"command"`
+	// Optional containers only add extras to the project (e.g. the gradle wrapper),
+	// they are skipped without a container runtime even if the manifest has no Skeleton.
+	Optional bool `yaml:"optional"`
+	// Cache stores what the container writes to /workspace and reuses it for the same template revision,
+	// build args and command. The container then runs for a placeholder project name, which is replaced
+	// with the name of the project afterwards. Only containers that write to /workspace without reading it can be cached.
+	Cache bool `yaml:"cache"`
+}
+
+// Skeleton renders the directory Source into Target (rendered, empty is the project directory) like the directory of a repeat,
+// then applies the Moves (relative to Target). Source itself is not copied into the project.
+type Skeleton struct {
+	Source string `yaml:"source"`
+	Target string `yaml:"target"`
+	Moves  []Move `yaml:"moves"`
+}
+
+// Merge inserts the content of Source into Target by replacing the first occurrence of Placeholder.
+type Merge struct {
+	Source      string `yaml:"source"`
+	Target      string `yaml:"target"`
+	Placeholder string `yaml:"placeholder"`
+}
+
+// Move renames the file or directory Source to Target, missing parent directories of Target are created.
+type Move struct {
+	Source string `yaml:"source"`
+	Target string `yaml:"target"`
+}
+
+// Repeat renders the directory Source into Target once for every item of the comma separated Items.
+// Target, the files of Source and the Moves (relative to Target) are rendered with {{ .Item }} set to the item.
+// Source itself is not copied into the project.
+type Repeat struct {
+	Source string `yaml:"source"`
+	Target string `yaml:"target"`
+	Items  string `yaml:"items"`
+	Moves  []Move `yaml:"moves"`
+}
+
+// Patch merges the generated file Source into the existing file Target of the project instead of copying it.
+// Type is how the files are merged: PatchYAML, PatchMakefile or PatchAppend.
+// If Target does not exist, Source is used as it is.
+type Patch struct {
+	Source string `yaml:"source"`
+	Target string `yaml:"target"`
+	Type   string `yaml:"type"`
+}
+
+// IsComponent reports whether the manifest describes a component.
+func (m *Manifest) IsComponent() bool {
+	return m.Kind == KindComponent
+}
+
+// SupportsLanguage reports whether the component can be added to projects of the language.
+func (m *Manifest) SupportsLanguage(language string) bool {
+	return m.Language == AnyLanguage || strings.EqualFold(m.Language, language)
+}
+
+// Load reads and validates the manifest inside templateDir.
+func Load(fsys fs.FS, templateDir string) (*Manifest, error) {
+	manifestPath := path.Join(templateDir, FileName)
+
+	data, err := fs.ReadFile(fsys, manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading template manifest %s: %w", manifestPath, err)
+	}
+
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("error parsing template manifest %s: %w", manifestPath, err)
+	}
+
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("invalid template manifest %s: %w", manifestPath, err)
+	}
+	return &m, nil
+}
+
+func (m *Manifest) validate() error {
+	if m.Name == "" {
+		return fmt.Errorf("'name' is required")
+	}
+	if m.Language == "" {
+		return fmt.Errorf("'language' is required")
+	}
+
+	seen := make(map[string]struct{})
+	for _, variable := range m.Variables {
+		if variable.Name == "" {
+			return fmt.Errorf("every variable needs a 'name'")
+		}
+		if _, exists := seen[variable.Name]; exists {
+			return fmt.Errorf("variable '%s' is declared twice", variable.Name)
+		}
+		seen[variable.Name] = struct{}{}
+	}
+
+	for _, script := range m.Scripts {
+		if script.Path == "" {
+			return fmt.Errorf("every script needs a 'path'")
+		}
+	}
+
+	for _, container := range m.Containers {
+		if container.Dockerfile == "" || container.Image == "" {
+			return fmt.Errorf("every container needs a 'dockerfile' and 'image'")
+		}
+	}
+
+	if m.Skeleton != nil {
+		if m.Skeleton.Source == "" {
+			return fmt.Errorf("the skeleton needs a 'source'")
+		}
+		for _, move := range m.Skeleton.Moves {
+			if move.Source == "" || move.Target == "" {
+				return fmt.Errorf("every move needs a 'source' and 'target'")
+			}
+		}
+	}
+
+	for _, merge := range m.Merges {
+		if merge.Source == "" || merge.Target == "" || merge.Placeholder == "" {
+			return fmt.Errorf("every merge needs a 'source', 'target' and 'placeholder'")
+		}
+	}
+
+	for _, move := range m.Moves {
+		if move.Source == "" || move.Target == "" {
+			return fmt.Errorf("every move needs a 'source' and 'target'")
+		}
+	}
+
+	for _, repeat := range m.Repeats {
+		if repeat.Source == "" || repeat.Target == "" || repeat.Items == "" {
+			return fmt.Errorf("every repeat needs a 'source', 'target' and 'items'")
+		}
+		for _, move := range repeat.Moves {
+			if move.Source == "" || move.Target == "" {
+				return fmt.Errorf("every move needs a 'source' and 'target'")
+			}
+		}
+	}
+
+	if err := m.checkPaths(); err != nil {
+		return err
+	}
+
+	if m.Kind != "" && m.Kind != KindComponent {
+		return fmt.Errorf("unknown kind '%s', only '%s' is allowed", m.Kind, KindComponent)
+	}
+	if len(m.Patches) > 0 && !m.IsComponent() {
+		return fmt.Errorf("only components can declare 'patches'")
+	}
+	for _, patch := range m.Patches {
+		if patch.Source == "" || patch.Target == "" {
+			return fmt.Errorf("every patch needs a 'source' and 'target'")
+		}
+		switch patch.Type {
+		case PatchYAML, PatchMakefile, PatchAppend:
+		default:
+			return fmt.Errorf("unknown patch type '%s' for %s, allowed types are: %s, %s, %s", patch.Type, patch.Target, PatchYAML, PatchMakefile, PatchAppend)
+		}
+	}
+	return nil
+}
+
+// checkPaths fails if a path of the manifest is absolute or leaves the project, e.g. delete: ["../sibling"].
+// Paths are checked again once they are rendered, values of variables may be part of them.
+func (m *Manifest) checkPaths() error {
+	paths := map[string][]string{
+		"render": m.Render,
+		"hoist":  {m.Hoist},
+		"prune":  m.Prune,
+		"delete": m.Delete,
+	}
+	for _, script := range m.Scripts {
+		paths["scripts"] = append(paths["scripts"], script.Path)
+	}
+	for _, container := range m.Containers {
+		paths["containers"] = append(paths["containers"], container.Dockerfile)
+	}
+	if m.Skeleton != nil {
+		paths["skeleton"] = append([]string{m.Skeleton.Source, m.Skeleton.Target}, movePaths(m.Skeleton.Moves)...)
+	}
+	for _, merge := range m.Merges {
+		paths["merges"] = append(paths["merges"], merge.Source, merge.Target)
+	}
+	paths["moves"] = movePaths(m.Moves)
+	for _, repeat := range m.Repeats {
+		paths["repeats"] = append(paths["repeats"], repeat.Source, repeat.Target)
+		paths["repeats"] = append(paths["repeats"], movePaths(repeat.Moves)...)
+	}
+	for _, patch := range m.Patches {
+		paths["patches"] = append(paths["patches"], patch.Source, patch.Target)
+	}
+
+	for _, field := range utils.SortedKeys(paths) {
+		for _, p := range paths[field] {
+			if _, err := utils.ContainedPath(".", p); err != nil {
+				return fmt.Errorf("invalid '%s': %w", field, err)
+			}
+		}
+	}
+	return nil
+}
+
+func movePaths(moves []Move) []string {
+	paths := make([]string, 0, 2*len(moves))
+	for _, move := range moves {
+		paths = append(paths, move.Source, move.Target)
+	}
+	return paths
+}
+
+// ResolveVariables combines the declared defaults with the given overrides.
+// It fails if a required variable has no value or if an override targets an undeclared variable.
+func (m *Manifest) ResolveVariables(overrides map[string]string) (map[string]string, error) {
+	values := make(map[string]string, len(m.Variables))
+	declared := make(map[string]struct{}, len(m.Variables))
+
+	for _, variable := range m.Variables {
+		declared[variable.Name] = struct{}{}
+		value, ok := overrides[variable.Name]
+		if !ok {
+			value = variable.Default
+		}
+		if value == "" && variable.Required {
+			return nil, fmt.Errorf("template '%s' requires a value for the variable '%s' (%s), set it with --set %s=<value>",
+				m.Name, variable.Name, variable.Description, variable.Name)
+		}
+		if err := variable.checkChoice(value); err != nil {
+			return nil, fmt.Errorf("template '%s': %w", m.Name, err)
+		}
+		values[variable.Name] = value
+	}
+
+	for name := range overrides {
+		if _, ok := declared[name]; !ok {
+			return nil, fmt.Errorf("template '%s' has no variable '%s'. Declared variables are: %v", m.Name, name, m.VariableNames())
+		}
+	}
+	return values, nil
+}
+
+// checkChoice fails if the variable has choices and value (or an item of the list) is not one of them.
+func (v Variable) checkChoice(value string) error {
+	if len(v.Choices) == 0 || value == "" {
+		return nil
+	}
+
+	items := []string{value}
+	if v.List {
+		items = strings.Split(value, ",")
+	}
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !utils.Contains(v.Choices, item) {
+			return fmt.Errorf("invalid value '%s' for the variable '%s'. Allowed values are: %s",
+				item, v.Name, strings.Join(v.Choices, ", "))
+		}
+	}
+	return nil
+}
+
+// VariableNames returns the names of all declared variables.
+func (m *Manifest) VariableNames() []string {
+	names := make([]string, 0, len(m.Variables))
+	for _, variable := range m.Variables {
+		names = append(names, variable.Name)
+	}
+	return names
+}
+
+// Discovered is a manifest found by Discover together with the directory it lives in.
+type Discovered struct {
+	Dir      string
+	Manifest *Manifest
+}
+
+// Discover walks root and loads every manifest below it, sorted by directory.
+func Discover(fsys fs.FS, root string) ([]Discovered, error) {
+	var found []Discovered
+
+	err := fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() != FileName {
+			return nil
+		}
+
+		dir := path.Dir(p)
+		m, err := Load(fsys, dir)
+		if err != nil {
+			return err
+		}
+		found = append(found, Discovered{Dir: dir, Manifest: m})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].Dir < found[j].Dir })
+	return found, nil
+}
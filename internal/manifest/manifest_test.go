@@ -0,0 +1,42 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadRejectsPathsOutsideTheProject(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields string
+	}{
+		{name: "delete", fields: "delete:\n  - ../sibling\n"},
+		{name: "prune", fields: "prune:\n  - /tmp\n"},
+		{name: "hoist", fields: "hoist: ..\n"},
+		{name: "moves", fields: "moves:\n  - source: src\n    target: ../src\n"},
+		{name: "merges", fields: "merges:\n  - source: ../../.ssh\n    target: keys\n    placeholder: KEYS\n"},
+		{name: "patches", fields: "kind: component\npatches:\n  - source: compose.yaml\n    target: ../compose.yaml\n    type: yaml\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{
+				"go/" + FileName: {Data: []byte("name: go\nlanguage: go\n" + tt.fields)},
+			}
+			_, err := Load(fsys, "go")
+			if err == nil || !strings.Contains(err.Error(), "'"+tt.name+"'") {
+				t.Errorf("Load() error = %v, want an invalid '%s'", err, tt.name)
+			}
+		})
+	}
+}
+
+func TestLoadAcceptsPathsInsideTheProject(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go/" + FileName: {Data: []byte("name: go\nlanguage: go\ndelete:\n  - bin/../tmp\nmoves:\n  - source: '{{ .ProjectName }}'\n    target: src\n")},
+	}
+	if _, err := Load(fsys, "go"); err != nil {
+		t.Errorf("Load() error = %v", err)
+	}
+}
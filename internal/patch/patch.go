@@ -0,0 +1,245 @@
+package patch
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"craft/internal/manifest"
+	"craft/internal/utils"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Apply merges addition into the existing content of a file, the way the patch type describes it.
+// Everything that already exists is kept, only what is missing is added.
+func Apply(patchType string, existing, addition []byte) ([]byte, error) {
+	switch patchType {
+	case manifest.PatchYAML:
+		return mergeYAML(existing, addition)
+	case manifest.PatchMakefile:
+		return mergeMakefile(existing, addition), nil
+	case manifest.PatchAppend:
+		return appendLines(existing, addition), nil
+	default:
+		return nil, fmt.Errorf("unknown patch type '%s'", patchType)
+	}
+}
+
+// mergeYAML merges the mappings of addition into existing recursively and appends missing sequence items.
+// Values that exist in both are kept as they are in existing, comments and the order of existing are preserved.
+func mergeYAML(existing, addition []byte) ([]byte, error) {
+	var target, source yaml.Node
+	if err := yaml.Unmarshal(existing, &target); err != nil {
+		return nil, fmt.Errorf("error parsing the existing yaml: %v", err)
+	}
+	if err := yaml.Unmarshal(addition, &source); err != nil {
+		return nil, fmt.Errorf("error parsing the yaml to add: %v", err)
+	}
+
+	if len(source.Content) == 0 {
+		return existing, nil
+	}
+	if len(target.Content) == 0 {
+		return addition, nil
+	}
+	mergeNodes(target.Content[0], source.Content[0])
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&target); err != nil {
+		return nil, fmt.Errorf("error encoding the merged yaml: %v", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("error encoding the merged yaml: %v", err)
+	}
+	return separateTopLevelKeys(buf.Bytes()), nil
+}
+
+// separateTopLevelKeys puts blank lines between the top level keys, like in compose files. The encoder drops them.
+func separateTopLevelKeys(data []byte) []byte {
+	lines := strings.Split(string(data), "\n")
+	separated := make([]string, 0, len(lines))
+	for i, line := range lines {
+		topLevelKey := line != "" && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "-")
+		if i > 0 && topLevelKey && separated[len(separated)-1] != "" {
+			separated = append(separated, "")
+		}
+		separated = append(separated, line)
+	}
+	return []byte(strings.Join(separated, "\n"))
+}
+
+func mergeNodes(target, source *yaml.Node) {
+	switch {
+	case target.Kind == yaml.MappingNode && source.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(source.Content); i += 2 {
+			key, value := source.Content[i], source.Content[i+1]
+			if existing := mappingValue(target, key.Value); existing != nil {
+				mergeNodes(existing, value)
+			} else {
+				target.Content = append(target.Content, key, value)
+			}
+		}
+	case target.Kind == yaml.SequenceNode && source.Kind == yaml.SequenceNode:
+		for _, item := range source.Content {
+			if !containsNode(target.Content, item) {
+				target.Content = append(target.Content, item)
+			}
+		}
+	case target.Tag == "!!null" && source.Kind == yaml.MappingNode:
+		// an empty key (e.g. 'volumes:') is filled
+		*target = *source
+	}
+}
+
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func containsNode(nodes []*yaml.Node, node *yaml.Node) bool {
+	encoded, err := yaml.Marshal(node)
+	if err != nil {
+		return false
+	}
+	for _, candidate := range nodes {
+		if other, err := yaml.Marshal(candidate); err == nil && bytes.Equal(encoded, other) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	makeRule     = regexp.MustCompile(`^([^\s:#=][^:#=]*?)\s*::?(?:[^=]|$)`)
+	makeVariable = regexp.MustCompile(`^\s*(?:export\s+|override\s+)?([A-Za-z_][A-Za-z0-9_]*)\s*(?::=|::=|\?=|\+=|!=|=)`)
+	makePhony    = regexp.MustCompile(`^\.PHONY\s*:(.*)$`)
+)
+
+// mergeMakefile appends the blocks (separated by blank lines) of addition whose rules or variables are not defined
+// in existing yet. The targets of its .PHONY declarations are added to the first .PHONY declaration of existing.
+func mergeMakefile(existing, addition []byte) []byte {
+	existingLines := strings.Split(strings.TrimRight(string(existing), "\n"), "\n")
+
+	defined := make(map[string]bool)
+	phonyLine := -1
+	for i, line := range existingLines {
+		if match := makePhony.FindStringSubmatch(line); match != nil {
+			if phonyLine == -1 {
+				phonyLine = i
+			}
+			continue
+		}
+		if match := makeVariable.FindStringSubmatch(line); match != nil {
+			defined["$"+match[1]] = true
+			continue
+		}
+		if match := makeRule.FindStringSubmatch(line); match != nil {
+			for _, target := range strings.Fields(match[1]) {
+				defined[target] = true
+			}
+		}
+	}
+
+	var phony []string
+	var blocks []string
+	for _, block := range strings.Split(strings.TrimSpace(string(addition)), "\n\n") {
+		var lines []string
+		skip := false
+		decided := false
+		for _, line := range strings.Split(block, "\n") {
+			if match := makePhony.FindStringSubmatch(line); match != nil {
+				phony = append(phony, strings.Fields(match[1])...)
+				continue
+			}
+			lines = append(lines, line)
+
+			// the first rule or variable of a block decides whether it is added
+			if decided || strings.HasPrefix(line, "\t") || strings.HasPrefix(strings.TrimSpace(line), "#") {
+				continue
+			}
+			if match := makeVariable.FindStringSubmatch(line); match != nil {
+				skip, decided = defined["$"+match[1]], true
+			} else if match := makeRule.FindStringSubmatch(line); match != nil {
+				skip, decided = allDefined(defined, strings.Fields(match[1])), true
+			}
+		}
+		if !skip && strings.TrimSpace(strings.Join(lines, "\n")) != "" {
+			blocks = append(blocks, strings.Join(lines, "\n"))
+		}
+	}
+
+	var missingPhony []string
+	for _, target := range phony {
+		if !phonyDeclared(existingLines, target) && !utils.Contains(missingPhony, target) {
+			missingPhony = append(missingPhony, target)
+		}
+	}
+	if len(missingPhony) > 0 {
+		if phonyLine >= 0 {
+			existingLines[phonyLine] = strings.TrimRight(existingLines[phonyLine], " ") + " " + strings.Join(missingPhony, " ")
+		} else {
+			blocks = append([]string{".PHONY: " + strings.Join(missingPhony, " ")}, blocks...)
+		}
+	}
+
+	result := strings.Join(existingLines, "\n")
+	if len(blocks) > 0 {
+		result += "\n\n" + strings.Join(blocks, "\n\n")
+	}
+	return []byte(result + "\n")
+}
+
+func allDefined(defined map[string]bool, targets []string) bool {
+	for _, target := range targets {
+		if !defined[target] {
+			return false
+		}
+	}
+	return true
+}
+
+func phonyDeclared(lines []string, target string) bool {
+	for _, line := range lines {
+		if match := makePhony.FindStringSubmatch(line); match != nil {
+			for _, declared := range strings.Fields(match[1]) {
+				if declared == target {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// appendLines appends the lines of addition that existing does not contain yet, e.g. for .gitignore files.
+func appendLines(existing, addition []byte) []byte {
+	present := make(map[string]bool)
+	for _, line := range strings.Split(string(existing), "\n") {
+		present[strings.TrimSpace(line)] = true
+	}
+
+	var missing []string
+	for _, line := range strings.Split(strings.TrimRight(string(addition), "\n"), "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" && !present[trimmed] {
+			missing = append(missing, line)
+			present[trimmed] = true
+		}
+	}
+	if len(missing) == 0 {
+		return existing
+	}
+
+	result := string(existing)
+	if result != "" && !strings.HasSuffix(result, "\n") {
+		result += "\n"
+	}
+	return []byte(result + strings.Join(missing, "\n") + "\n")
+}
@@ -0,0 +1,223 @@
+package patch
+
+import (
+	"strings"
+	"testing"
+
+	"craft/internal/manifest"
+)
+
+const composeFile = `services:
+  app:
+    build: .
+    # the port of the app
+    ports:
+      - "8080:8080"
+
+volumes:
+  data:
+`
+
+const postgresService = `services:
+  app:
+    depends_on:
+      - postgres
+  postgres:
+    image: postgres:17
+    volumes:
+      - pgdata:/var/lib/postgresql/data
+
+volumes:
+  pgdata:
+`
+
+func TestMergeYAML(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		addition string
+		want     string
+	}{
+		{
+			name:     "new service into an existing compose file",
+			existing: composeFile,
+			addition: postgresService,
+			want: `services:
+  app:
+    build: .
+    # the port of the app
+    ports:
+      - "8080:8080"
+    depends_on:
+      - postgres
+  postgres:
+    image: postgres:17
+    volumes:
+      - pgdata:/var/lib/postgresql/data
+
+volumes:
+  data:
+  pgdata:
+`,
+		},
+		{
+			name:     "existing values are kept",
+			existing: "services:\n  app:\n    image: app:1\n",
+			addition: "services:\n  app:\n    image: app:2\n",
+			want:     "services:\n  app:\n    image: app:1\n",
+		},
+		{
+			name:     "missing sequence items are appended",
+			existing: "ports:\n  - \"80:80\"\n",
+			addition: "ports:\n  - \"80:80\"\n  - \"443:443\"\n",
+			want:     "ports:\n  - \"80:80\"\n  - \"443:443\"\n",
+		},
+		{
+			name:     "empty key is filled",
+			existing: "services:\n  app:\n    image: app\nvolumes:\n",
+			addition: "volumes:\n  data:\n",
+			want:     "services:\n  app:\n    image: app\n\nvolumes:\n  data:\n",
+		},
+		{
+			name:     "empty existing file",
+			existing: "",
+			addition: postgresService,
+			want:     postgresService,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeYAML([]byte(tt.existing), []byte(tt.addition))
+			if err != nil {
+				t.Fatalf("mergeYAML() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("mergeYAML() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeYAMLInvalid(t *testing.T) {
+	if _, err := mergeYAML([]byte("services: [\n"), []byte(postgresService)); err == nil {
+		t.Error("mergeYAML() of invalid yaml succeeded")
+	}
+}
+
+const makefile = `.PHONY: build test
+
+APP := app
+
+build:
+	go build ./...
+
+test:
+	go test ./...
+`
+
+func TestMergeMakefile(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		addition string
+		want     string
+	}{
+		{
+			name:     "new target",
+			existing: makefile,
+			addition: ".PHONY: db\n\n# starts the database\ndb:\n\tdocker compose up -d postgres\n",
+			want:     ".PHONY: build test db\n\nAPP := app\n\nbuild:\n\tgo build ./...\n\ntest:\n\tgo test ./...\n\n# starts the database\ndb:\n\tdocker compose up -d postgres\n",
+		},
+		{
+			name:     "target that already exists",
+			existing: makefile,
+			addition: ".PHONY: test\n\ntest:\n\tgo test -race ./...\n",
+			want:     makefile,
+		},
+		{
+			name:     "variable that already exists",
+			existing: makefile,
+			addition: "APP ?= other\n",
+			want:     makefile,
+		},
+		{
+			name:     "new variable",
+			existing: makefile,
+			addition: "DB_URL ?= postgres://localhost\n",
+			want:     makefile + "\nDB_URL ?= postgres://localhost\n",
+		},
+		{
+			name:     "no .PHONY in the existing Makefile",
+			existing: "build:\n\tgo build ./...\n",
+			addition: ".PHONY: db\n\ndb:\n\tdocker compose up -d\n",
+			want:     "build:\n\tgo build ./...\n\n.PHONY: db\n\ndb:\n\tdocker compose up -d\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(mergeMakefile([]byte(tt.existing), []byte(tt.addition))); got != tt.want {
+				t.Errorf("mergeMakefile() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppendLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		addition string
+		want     string
+	}{
+		{name: "missing lines", existing: "bin/\n", addition: "bin/\n.env\n", want: "bin/\n.env\n"},
+		{name: "no trailing newline", existing: "bin/", addition: ".env\n", want: "bin/\n.env\n"},
+		{name: "nothing missing", existing: "bin/\n.env\n", addition: ".env\n", want: "bin/\n.env\n"},
+		{name: "empty file", existing: "", addition: ".env\n\n.env\n", want: ".env\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(appendLines([]byte(tt.existing), []byte(tt.addition))); got != tt.want {
+				t.Errorf("appendLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestApplyIdempotent applies every patch type twice, the second time must not change anything.
+func TestApplyIdempotent(t *testing.T) {
+	tests := []struct {
+		patchType string
+		existing  string
+		addition  string
+	}{
+		{patchType: manifest.PatchYAML, existing: composeFile, addition: postgresService},
+		{patchType: manifest.PatchMakefile, existing: makefile, addition: ".PHONY: db\n\n# starts the database\ndb:\n\tdocker compose up -d postgres\n\nDB_URL ?= postgres://localhost\n"},
+		{patchType: manifest.PatchAppend, existing: "bin/\n", addition: ".env\n*.log\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.patchType, func(t *testing.T) {
+			once, err := Apply(tt.patchType, []byte(tt.existing), []byte(tt.addition))
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			twice, err := Apply(tt.patchType, once, []byte(tt.addition))
+			if err != nil {
+				t.Fatalf("Apply() again error = %v", err)
+			}
+			if string(twice) != string(once) {
+				t.Errorf("Apply() again =\n%s\nwant\n%s", twice, once)
+			}
+		})
+	}
+}
+
+func TestApplyUnknownType(t *testing.T) {
+	_, err := Apply("json", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "unknown patch type") {
+		t.Errorf("Apply() error = %v, want an unknown patch type", err)
+	}
+}
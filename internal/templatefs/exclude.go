@@ -0,0 +1,56 @@
+package templatefs
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Exclude hides the directories (and everything below them) of fsys, e.g. templates with an invalid manifest.
+func Exclude(fsys fs.FS, dirs ...string) fs.FS {
+	if len(dirs) == 0 {
+		return fsys
+	}
+	return &excluded{fsys: fsys, dirs: dirs}
+}
+
+type excluded struct {
+	fsys fs.FS
+	dirs []string
+}
+
+// Open implements fs.FS.
+func (e *excluded) Open(name string) (fs.File, error) {
+	if e.hidden(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return e.fsys.Open(name)
+}
+
+// ReadDir implements fs.ReadDirFS, the hidden directories are left out.
+func (e *excluded) ReadDir(name string) ([]fs.DirEntry, error) {
+	if e.hidden(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	entries, err := fs.ReadDir(e.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := entries[:0]
+	for _, entry := range entries {
+		if !e.hidden(path.Join(name, entry.Name())) {
+			visible = append(visible, entry)
+		}
+	}
+	return visible, nil
+}
+
+func (e *excluded) hidden(name string) bool {
+	for _, dir := range e.dirs {
+		if name == dir || strings.HasPrefix(name, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package templatefs
+
+import (
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"craft/internal/manifest"
+)
+
+// Layer is a named filesystem, the name tells the user where a template comes from.
+type Layer struct {
+	Name string
+	FS   fs.FS
+}
+
+// Layered merges several filesystems into one, earlier layers shadow later ones.
+//
+// Directories are merged, except for template directories (directories containing a manifest.FileName):
+// a template is always taken as a whole from the highest layer that defines it, so files of a
+// shadowed template never leak into the one that replaces it.
+type Layered struct {
+	layers []Layer
+}
+
+// NewLayered creates a layered filesystem, the first layer has the highest priority.
+func NewLayered(layers ...Layer) *Layered {
+	return &Layered{layers: layers}
+}
+
+// Open implements fs.FS.
+func (l *Layered) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if owner, ok := l.owner(name); ok {
+		return owner.FS.Open(name)
+	}
+
+	for _, layer := range l.layers {
+		file, err := layer.FS.Open(name)
+		if err != nil {
+			continue
+		}
+
+		info, err := file.Stat()
+		if err != nil || !info.IsDir() {
+			return file, err
+		}
+
+		entries, err := l.ReadDir(name)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &dir{File: file, entries: entries}, nil
+	}
+
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir implements fs.ReadDirFS by merging the entries of all layers.
+func (l *Layered) ReadDir(name string) ([]fs.DirEntry, error) {
+	if owner, ok := l.owner(name); ok {
+		return fs.ReadDir(owner.FS, name)
+	}
+
+	merged := make(map[string]fs.DirEntry)
+	found := false
+	for i := len(l.layers) - 1; i >= 0; i-- {
+		entries, err := fs.ReadDir(l.layers[i].FS, name)
+		if err != nil {
+			continue
+		}
+		found = true
+		for _, entry := range entries {
+			merged[entry.Name()] = entry
+		}
+	}
+
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(merged))
+	for _, entry := range merged {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// Source returns the name of the layer the file or directory is taken from.
+func (l *Layered) Source(name string) string {
+	if owner, ok := l.owner(name); ok {
+		return owner.Name
+	}
+	for _, layer := range l.layers {
+		if _, err := fs.Stat(layer.FS, name); err == nil {
+			return layer.Name
+		}
+	}
+	return ""
+}
+
+// owner returns the layer that provides the template directory name belongs to (if any).
+func (l *Layered) owner(name string) (Layer, bool) {
+	if name == "." {
+		return Layer{}, false
+	}
+
+	segments := strings.Split(name, "/")
+	for i := 1; i <= len(segments); i++ {
+		candidate := path.Join(path.Join(segments[:i]...), manifest.FileName)
+		for _, layer := range l.layers {
+			if _, err := fs.Stat(layer.FS, candidate); err == nil {
+				return layer, true
+			}
+		}
+	}
+	return Layer{}, false
+}
+
+// dir is an opened directory whose entries are merged from all layers.
+type dir struct {
+	fs.File
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *dir) ReadDir(count int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if count <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(remaining) {
+		count = len(remaining)
+	}
+	d.offset += count
+	return remaining[:count], nil
+}
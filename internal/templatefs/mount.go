@@ -0,0 +1,130 @@
+package templatefs
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// Mount exposes fsys below prefix, e.g. Mount("templates", os.DirFS("~/.config/craft/templates"))
+// makes ~/.config/craft/templates/go/template.yaml available as templates/go/template.yaml.
+func Mount(prefix string, fsys fs.FS) fs.FS {
+	return &mounted{prefix: prefix, fsys: fsys}
+}
+
+// MountDir mounts a directory of the host below prefix, it fails if the directory does not exist.
+func MountDir(prefix, dir string) (fs.FS, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading template directory %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("template directory %s is not a directory", dir)
+	}
+	return Mount(prefix, os.DirFS(dir)), nil
+}
+
+type mounted struct {
+	prefix string
+	fsys   fs.FS
+}
+
+func (m *mounted) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if name == m.prefix || strings.HasPrefix(name, m.prefix+"/") {
+		rel := strings.TrimPrefix(strings.TrimPrefix(name, m.prefix), "/")
+		if rel != "" {
+			return m.fsys.Open(rel)
+		}
+		root, err := m.fsys.Open(".")
+		if err != nil {
+			return nil, err
+		}
+		return &mountPoint{File: root, name: path.Base(m.prefix)}, nil
+	}
+
+	// the parent directories of the prefix only contain the next segment of the prefix
+	if name == "." || strings.HasPrefix(m.prefix, name+"/") {
+		child := strings.TrimPrefix(m.prefix, name+"/")
+		if name == "." {
+			child = m.prefix
+		}
+		child, _, _ = strings.Cut(child, "/")
+		return &syntheticDir{name: name, child: child}, nil
+	}
+
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// mountPoint is the root of the mounted filesystem, it is named after the last segment of the prefix instead of ".".
+type mountPoint struct {
+	fs.File
+	name string
+}
+
+func (p *mountPoint) Stat() (fs.FileInfo, error) {
+	info, err := p.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return renamedInfo{FileInfo: info, name: p.name}, nil
+}
+
+func (p *mountPoint) ReadDir(count int) ([]fs.DirEntry, error) {
+	dir, ok := p.File.(fs.ReadDirFile)
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: p.name, Err: fs.ErrInvalid}
+	}
+	return dir.ReadDir(count)
+}
+
+type renamedInfo struct {
+	fs.FileInfo
+	name string
+}
+
+func (i renamedInfo) Name() string { return i.name }
+
+// syntheticDir is a directory that only exists to lead to the mount point.
+type syntheticDir struct {
+	name  string
+	child string
+	read  bool
+}
+
+func (d *syntheticDir) Stat() (fs.FileInfo, error) {
+	return syntheticInfo{name: path.Base(d.name)}, nil
+}
+func (d *syntheticDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: fs.ErrInvalid}
+}
+func (d *syntheticDir) Close() error { return nil }
+
+func (d *syntheticDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	if d.read {
+		if count > 0 {
+			return nil, io.EOF
+		}
+		return nil, nil
+	}
+	d.read = true
+	return []fs.DirEntry{fs.FileInfoToDirEntry(syntheticInfo{name: d.child})}, nil
+}
+
+type syntheticInfo struct {
+	name string
+}
+
+func (i syntheticInfo) Name() string       { return i.name }
+func (i syntheticInfo) Size() int64        { return 0 }
+func (i syntheticInfo) Mode() fs.FileMode  { return fs.ModeDir | 0555 }
+func (i syntheticInfo) ModTime() time.Time { return time.Time{} }
+func (i syntheticInfo) IsDir() bool        { return true }
+func (i syntheticInfo) Sys() any           { return nil }
@@ -0,0 +1,103 @@
+package templatefs
+
+import (
+	"errors"
+	"io/fs"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func names(entries []fs.DirEntry) []string {
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func newTestLayered() *Layered {
+	user := fstest.MapFS{
+		"go/template.yaml":   {Data: []byte("name: user-go\nlanguage: go\n")},
+		"go/main.go":         {Data: []byte("package user\n")},
+		"rust/template.yaml": {Data: []byte("name: rust\nlanguage: rust\n")},
+	}
+	builtIn := fstest.MapFS{
+		"templates/go/template.yaml":     {Data: []byte("name: go\nlanguage: go\n")},
+		"templates/go/main.go":           {Data: []byte("package main\n")},
+		"templates/go/Makefile":          {Data: []byte("build:\n")},
+		"templates/python/template.yaml": {Data: []byte("name: python\nlanguage: python\n")},
+	}
+	return NewLayered(Layer{Name: "user", FS: Mount("templates", user)}, Layer{Name: "built-in", FS: builtIn})
+}
+
+func TestLayeredShadowing(t *testing.T) {
+	layered := newTestLayered()
+
+	content, err := fs.ReadFile(layered, "templates/go/main.go")
+	if err != nil || string(content) != "package user\n" {
+		t.Errorf("ReadFile() of a shadowed file = %q, %v, want the one of the user", content, err)
+	}
+	if _, err := fs.Stat(layered, "templates/go/Makefile"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat() of a file of the shadowed template error = %v, want it to not exist", err)
+	}
+
+	entries, err := fs.ReadDir(layered, "templates")
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if got, want := names(entries), []string{"go", "python", "rust"}; !slices.Equal(got, want) {
+		t.Errorf("ReadDir() = %q, want %q", got, want)
+	}
+	if entries, err = fs.ReadDir(layered, "templates/go"); err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if got, want := names(entries), []string{"main.go", "template.yaml"}; !slices.Equal(got, want) {
+		t.Errorf("ReadDir() of the shadowing template = %q, want %q", got, want)
+	}
+
+	for name, want := range map[string]string{
+		"templates/go/main.go":           "user",
+		"templates/rust":                 "user",
+		"templates/python/template.yaml": "built-in",
+		"templates/java":                 "",
+	} {
+		if got := layered.Source(name); got != want {
+			t.Errorf("Source(%q) = %q, want %q", name, got, want)
+		}
+	}
+
+	if err := fstest.TestFS(layered, "templates/go/main.go", "templates/python/template.yaml", "templates/rust/template.yaml"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestExclude(t *testing.T) {
+	fsys := fstest.MapFS{
+		"templates/go/template.yaml":  {Data: []byte("name: go\n")},
+		"templates/go/main.go":        {Data: []byte("package main\n")},
+		"templates/gox/template.yaml": {Data: []byte("name: gox\n")},
+	}
+	excluded := Exclude(fsys, "templates/go")
+
+	for _, name := range []string{"templates/go", "templates/go/main.go"} {
+		if _, err := excluded.Open(name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Open(%q) error = %v, want it to not exist", name, err)
+		}
+	}
+	if _, err := fs.ReadFile(excluded, "templates/gox/template.yaml"); err != nil {
+		t.Errorf("ReadFile() of a directory with the excluded one as prefix error = %v", err)
+	}
+
+	entries, err := fs.ReadDir(excluded, "templates")
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if got, want := names(entries), []string{"gox"}; !slices.Equal(got, want) {
+		t.Errorf("ReadDir() = %q, want %q", got, want)
+	}
+
+	if _, ok := Exclude(fsys).(fstest.MapFS); !ok {
+		t.Error("Exclude() without directories does not return the filesystem")
+	}
+}
@@ -0,0 +1,70 @@
+package templating
+
+import (
+	"os"
+	"os/exec"
+	"os/user"
+	"strings"
+
+	"craft/internal/utils"
+)
+
+// Context is the data every template file is rendered with.
+// Templates access it with the usual dot notation, e.g. {{ .ProjectName }} or {{ .Versions.go }}.
+type Context struct {
+	ProjectName  string
+	Language     string
+	Dependencies []string
+	BuildTool    string
+	Framework    string
+	// Stacks are the libraries the project is built on, e.g. axum and tokio for rust: {{ if contains .Stacks "axum" }}.
+	Stacks []string
+	// Kind is the kind of project, e.g. cli or http for go.
+	Kind       string
+	Author     string
+	ModulePath string
+	Versions   map[string]string
+	// Variables holds the values of the variables declared in the template manifest.
+	Variables map[string]string
+	// Item is the item the directory of a repeat is rendered for, e.g. the name of a module.
+	Item string
+	// Runtime is the container runtime the generated files use, e.g. {{ .Runtime }} compose up.
+	Runtime string
+	// NoContainers is set if the containers of the template were not run and its skeleton was rendered instead.
+	NoContainers bool
+}
+
+// NewContext creates a context for the given project with sensible defaults:
+// the module path equals the project name and the author is taken from the local git config.
+func NewContext(projectName, language string, dependencies []string) Context {
+	return Context{
+		ProjectName:  projectName,
+		Language:     language,
+		Dependencies: dependencies,
+		Author:       DefaultAuthor(),
+		ModulePath:   projectName,
+		Versions:     map[string]string{},
+		Variables:    map[string]string{},
+	}
+}
+
+// HasDependency reports whether the dependency was requested (case-insensitive).
+// Usage in a template: {{ if .HasDependency "quarkus" }} ... {{ end }}
+func (c Context) HasDependency(dependency string) bool {
+	return utils.ContainsStringInsensitive(c.Dependencies, dependency)
+}
+
+// DefaultAuthor returns the git user name if configured, otherwise the name of the current OS user.
+func DefaultAuthor() string {
+	if out, err := exec.Command("git", "config", "--get", "user.name").Output(); err == nil {
+		if name := strings.TrimSpace(string(out)); name != "" {
+			return name
+		}
+	}
+
+	if current, err := user.Current(); err == nil && current.Username != "" {
+		return current.Username
+	}
+
+	return os.Getenv("USER")
+}
@@ -0,0 +1,128 @@
+package templating
+
+import (
+	"strings"
+	"text/template"
+	"unicode"
+
+	"craft/internal/utils"
+)
+
+// funcMap contains the helper functions available in every template.
+var funcMap = template.FuncMap{
+	"kebab":    KebabCase,
+	"snake":    SnakeCase,
+	"pascal":   PascalCase,
+	"camel":    CamelCase,
+	"upper":    strings.ToUpper,
+	"lower":    strings.ToLower,
+	"trim":     strings.TrimSpace,
+	"replace":  strings.ReplaceAll,
+	"join":     func(sep string, items []string) string { return strings.Join(items, sep) },
+	"split":    Split,
+	"last":     Last,
+	"initial":  Initial,
+	"contains": utils.ContainsStringInsensitive,
+	"default": func(fallback, value string) string {
+		if value == "" {
+			return fallback
+		}
+		return value
+	},
+}
+
+// Split splits a separated list like "web, actuator" into its trimmed, non-empty items.
+func Split(sep, s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Last returns the last item of items, e.g. the application module of {{ last (split "," .Variables.Modules) }}.
+func Last(items []string) string {
+	if len(items) == 0 {
+		return ""
+	}
+	return items[len(items)-1]
+}
+
+// Initial returns all items but the last one.
+func Initial(items []string) []string {
+	if len(items) == 0 {
+		return nil
+	}
+	return items[:len(items)-1]
+}
+
+// KebabCase converts "My ProjectName" to "my-project-name".
+func KebabCase(s string) string {
+	return strings.Join(lowerWords(s), "-")
+}
+
+// SnakeCase converts "My ProjectName" to "my_project_name".
+func SnakeCase(s string) string {
+	return strings.Join(lowerWords(s), "_")
+}
+
+// PascalCase converts "my-project-name" to "MyProjectName".
+func PascalCase(s string) string {
+	var sb strings.Builder
+	for _, word := range lowerWords(s) {
+		sb.WriteString(capitalize(word))
+	}
+	return sb.String()
+}
+
+// CamelCase converts "my-project-name" to "myProjectName".
+func CamelCase(s string) string {
+	pascal := PascalCase(s)
+	if pascal == "" {
+		return ""
+	}
+	runes := []rune(pascal)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+func capitalize(word string) string {
+	runes := []rune(word)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// lowerWords splits s into lower-cased words. Words are separated by any
+// non-alphanumeric character and by lower-to-upper case transitions ("myProject" -> "my", "project").
+func lowerWords(s string) []string {
+	var words []string
+	var current []rune
+
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, strings.ToLower(string(current)))
+			current = current[:0]
+		}
+	}
+
+	runes := []rune(s)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if unicode.IsUpper(r) && len(current) > 0 {
+			previous := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextIsLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+
+	return words
+}
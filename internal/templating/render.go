@@ -0,0 +1,82 @@
+package templating
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"craft/internal/constants"
+	"craft/internal/utils"
+)
+
+// Render executes the template content with the given context.
+// Referencing a key that does not exist (e.g. a missing version) is an error instead of rendering "<no value>".
+func Render(name string, content []byte, ctx Context) ([]byte, error) {
+	tmpl, err := template.New(name).Funcs(funcMap).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing template %s: %w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, ctx); err != nil {
+		return nil, fmt.Errorf("error rendering template %s: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// RenderString renders a single templated value, e.g. a script argument of a manifest.
+func RenderString(value string, ctx Context) (string, error) {
+	rendered, err := Render(value, []byte(value), ctx)
+	if err != nil {
+		return "", err
+	}
+	return string(rendered), nil
+}
+
+// RenderFile renders the file relPath below templateDir in fsys and writes the result into projectHostDir
+// (same relative path, constants.TemplateFileSuffix trimmed).
+func RenderFile(fsys fs.FS, templateDir, relPath, projectHostDir string, ctx Context) error {
+	sourcePath := path.Join(templateDir, filepath.ToSlash(relPath))
+
+	content, err := fs.ReadFile(fsys, sourcePath)
+	if err != nil {
+		return fmt.Errorf("error reading template %s: %w", sourcePath, err)
+	}
+
+	rendered, err := Render(relPath, content, ctx)
+	if err != nil {
+		return err
+	}
+
+	hostFilePath := filepath.Join(projectHostDir, strings.TrimSuffix(relPath, constants.TemplateFileSuffix))
+	return utils.WriteFile(hostFilePath, rendered)
+}
+
+// ListTemplateFiles returns the paths (relative to templateDir) of all files ending with constants.TemplateFileSuffix.
+func ListTemplateFiles(fsys fs.FS, templateDir string) ([]string, error) {
+	var templateFiles []string
+
+	err := fs.WalkDir(fsys, templateDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), constants.TemplateFileSuffix) {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(templateDir, p)
+		if err != nil {
+			return fmt.Errorf("error calculating relative path: %w", err)
+		}
+		templateFiles = append(templateFiles, relPath)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return templateFiles, nil
+}
@@ -0,0 +1,318 @@
+package updater
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"craft/internal/common"
+	"craft/internal/constants"
+	"craft/internal/diff"
+	"craft/internal/generation"
+	"craft/internal/generator"
+	"craft/internal/utils"
+)
+
+// Actions taken for a file of the project, used in the report.
+const (
+	ActionUpdated        = "updated"
+	ActionMerged         = "merged"
+	ActionConflict       = "conflict"
+	ActionAdded          = "added"
+	ActionRemoved        = "removed"
+	ActionKept           = "kept"
+	ActionNewVersion     = "new version"
+	ActionDeletedLocally = "deleted locally"
+)
+
+// Change is what happened to a file of the project.
+type Change struct {
+	Path   string
+	Action string
+	Detail string
+}
+
+// Template is a version of the template to render, the templates filesystem and the directory of the template in it.
+type Template struct {
+	FS   fs.FS
+	Path string
+	// Source is recorded in the generation manifest, it is only set for templates from git repositories.
+	Source *common.TemplateSource
+}
+
+// Updater re-applies the current version of the template a project was generated from.
+//
+// Both the original (base) and the current version of the template are rendered with the recorded values.
+// The changes between them are merged into the project with a three-way merge, so changes the user made are kept.
+// If the original version is not available (built-in templates or template directories are not versioned),
+// the recorded hashes tell whether a file was changed by the user: unchanged files are replaced and
+// the current version of changed files is written next to them.
+type Updater struct {
+	ProjectDir string
+	Recorded   *generation.Manifest
+	// Base is the template version the project was generated from, nil if it is not available.
+	Base    *Template
+	Current Template
+	// Variables overrides recorded variable values or sets variables the current template added.
+	Variables map[string]string
+
+	// containers is set if the current template has containers. They are not run, the skeleton is rendered
+	// in their place, so files only they create are in the project but not in the rendered versions.
+	containers bool
+}
+
+// Run updates the project and returns the changes, ordered by path.
+func (u *Updater) Run() ([]Change, error) {
+	var baseDir string
+	if u.Base != nil {
+		// the original versions are recorded
+		dir, err := u.renderWith(u.generator(*u.Base, u.Recorded.Versions, nil))
+		if err != nil {
+			return nil, fmt.Errorf("error rendering the original template version: %v", err)
+		}
+		defer os.RemoveAll(dir)
+		baseDir = dir
+	}
+
+	// the current template brings its own versions
+	current := u.generator(u.Current, nil, u.Variables)
+	currentDir, err := u.renderWith(current)
+	if err != nil {
+		return nil, fmt.Errorf("error rendering the current template version: %v", err)
+	}
+	defer os.RemoveAll(currentDir)
+
+	m, err := current.LoadManifest()
+	if err != nil {
+		return nil, err
+	}
+	u.containers = len(m.Containers) > 0
+
+	changes, err := u.apply(baseDir, currentDir)
+	if err != nil {
+		return changes, err
+	}
+	return changes, current.RecordGeneration(m, currentDir, u.ProjectDir)
+}
+
+// generator creates a generator with the recorded context of the project. The containers of the template
+// are not run, rendering a version must neither need a container runtime nor depend on what the containers fetch.
+func (u *Updater) generator(template Template, versions, variables map[string]string) *generator.Generator {
+	ctx := u.Recorded.Context()
+	for tool, version := range versions {
+		ctx.Versions[tool] = version
+	}
+
+	overrides := make(map[string]string, len(u.Recorded.Variables)+len(variables))
+	for name, value := range u.Recorded.Variables {
+		overrides[name] = value
+	}
+	for name, value := range variables {
+		overrides[name] = value
+	}
+
+	return &generator.Generator{
+		TemplatesFileSystem: template.FS,
+		TemplatePath:        template.Path,
+		Context:             ctx,
+		Options:             common.Options{Variables: overrides, TemplateSource: template.Source, Skeleton: true},
+	}
+}
+
+// renderWith renders the template of gen into a new temporary directory.
+func (u *Updater) renderWith(gen *generator.Generator) (string, error) {
+	m, err := gen.LoadManifest()
+	if err != nil {
+		return "", err
+	}
+
+	// variables the template version does not declare (any more) are dropped
+	declared := m.VariableNames()
+	for name := range gen.Options.Variables {
+		if !utils.Contains(declared, name) {
+			delete(gen.Options.Variables, name)
+		}
+	}
+
+	dir, err := os.MkdirTemp("", constants.ToolName+"-update-")
+	if err != nil {
+		return "", fmt.Errorf("could not create a temporary directory: %v", err)
+	}
+
+	if _, err := gen.Render(dir); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+// apply merges the difference between the rendered base and current template versions into the project.
+func (u *Updater) apply(baseDir, currentDir string) ([]Change, error) {
+	currentFiles, err := generation.HashFiles(currentDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// without the original version, the recorded hashes describe it
+	baseFiles := u.Recorded.Files
+	if baseDir != "" {
+		if baseFiles, err = generation.HashFiles(baseDir); err != nil {
+			return nil, err
+		}
+	}
+
+	paths := make([]string, 0, len(currentFiles)+len(baseFiles))
+	for path := range currentFiles {
+		paths = append(paths, path)
+	}
+	for path := range baseFiles {
+		if _, ok := currentFiles[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var changes []Change
+	for _, path := range paths {
+		change, err := u.applyFile(path, baseDir, currentDir, baseFiles[path], currentFiles[path])
+		if err != nil {
+			return changes, err
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+	return changes, nil
+}
+
+// applyFile updates a single file of the project, it returns nil if nothing had to be done.
+func (u *Updater) applyFile(path, baseDir, currentDir, baseHash, currentHash string) (*Change, error) {
+	projectPath := filepath.Join(u.ProjectDir, filepath.FromSlash(path))
+	currentPath := filepath.Join(currentDir, filepath.FromSlash(path))
+
+	if baseHash == currentHash {
+		// the template did not change the file
+		return nil, nil
+	}
+
+	project, err := os.ReadFile(projectPath)
+	projectExists := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error reading %s: %v", projectPath, err)
+	}
+	projectHash := ""
+	if projectExists {
+		projectHash = generation.Hash(project)
+	}
+
+	switch {
+	case currentHash == "":
+		// the template removed the file
+		if !projectExists {
+			return nil, nil
+		}
+		if baseDir == "" && u.containerOutput() {
+			// the recorded file may have been created by a container, which did not run
+			return nil, nil
+		}
+		if projectHash != baseHash {
+			return &Change{Path: path, Action: ActionKept, Detail: "removed from the template, but changed in the project"}, nil
+		}
+		if err := utils.RemoveFileFromHost(projectPath); err != nil {
+			return nil, fmt.Errorf("error removing %s: %v", projectPath, err)
+		}
+		return &Change{Path: path, Action: ActionRemoved}, nil
+
+	case !projectExists && baseHash != "":
+		return &Change{Path: path, Action: ActionDeletedLocally, Detail: "the template changed it, it was not restored"}, nil
+
+	case !projectExists:
+		if err := utils.CopyFile(currentPath, projectPath); err != nil {
+			return nil, err
+		}
+		return &Change{Path: path, Action: ActionAdded}, nil
+
+	case projectHash == currentHash:
+		return nil, nil
+
+	case baseDir == "" && u.containerOutput():
+		// the recorded hashes describe what the containers created, the rendered version is the skeleton
+		// that stands in for them: a difference is no change of the template, the file is left alone
+		return &Change{Path: path, Action: ActionKept, Detail: "it may have been created by a container, the template version was not applied"}, nil
+
+	case projectHash == baseHash:
+		// not changed by the user, the current version replaces it
+		if err := utils.CopyFile(currentPath, projectPath); err != nil {
+			return nil, err
+		}
+		return &Change{Path: path, Action: ActionUpdated}, nil
+	}
+
+	current, err := os.ReadFile(currentPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if baseDir == "" || baseHash == "" {
+		// both changed, but there is no common version to merge them
+		if err := utils.WriteFile(projectPath+generator.KeepBothSuffix, current); err != nil {
+			return nil, err
+		}
+		return &Change{Path: path, Action: ActionNewVersion, Detail: "changed in the project and the template, see " + path + generator.KeepBothSuffix}, nil
+	}
+
+	base, err := os.ReadFile(filepath.Join(baseDir, filepath.FromSlash(path)))
+	if err != nil {
+		return nil, err
+	}
+
+	merged, conflicts := diff.Merge(string(base), string(project), string(current), "project", "template")
+	if bytes.Equal([]byte(merged), project) {
+		return nil, nil
+	}
+	if err := utils.WriteFile(projectPath, []byte(merged)); err != nil {
+		return nil, err
+	}
+
+	if conflicts > 0 {
+		return &Change{Path: path, Action: ActionConflict, Detail: fmt.Sprintf("%d conflict(s), resolve the conflict markers", conflicts)}, nil
+	}
+	return &Change{Path: path, Action: ActionMerged}, nil
+}
+
+// containerOutput reports whether the project was generated by running the containers of the template.
+// Its files then differ from the rendered versions, which use the skeleton.
+func (u *Updater) containerOutput() bool {
+	return u.containers && !u.Recorded.NoContainers
+}
+
+// HasConflicts reports whether any change needs the attention of the user.
+func HasConflicts(changes []Change) bool {
+	for _, change := range changes {
+		if change.Action == ActionConflict || change.Action == ActionNewVersion {
+			return true
+		}
+	}
+	return false
+}
+
+// PrintReport prints the changes of an update.
+func PrintReport(projectDir string, changes []Change) {
+	if len(changes) == 0 {
+		fmt.Printf("%v is up to date with its template\n", projectDir)
+		return
+	}
+
+	fmt.Printf("Updated %v:\n", projectDir)
+	for _, change := range changes {
+		line := fmt.Sprintf("  %-16s %s", change.Action, change.Path)
+		if change.Detail != "" {
+			line += " (" + change.Detail + ")"
+		}
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+}
@@ -0,0 +1,242 @@
+package updater
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"craft/internal/common"
+	"craft/internal/container"
+	"craft/internal/generation"
+	"craft/internal/generator"
+	"craft/internal/templating"
+)
+
+const templateManifest = "name: app\nlanguage: go\n"
+
+// templateFS returns a template directory "app" with the files.
+func templateFS(files map[string]string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for name, content := range files {
+		fsys["app/"+name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	return fsys
+}
+
+// generate renders the template into a new project directory and returns the recorded generation.
+func generate(t *testing.T, fsys fstest.MapFS) (string, *generation.Manifest) {
+	t.Helper()
+	projectDir := t.TempDir()
+
+	ctx := templating.NewContext("demo", "go", nil)
+	ctx.Runtime = container.Docker
+	g := &generator.Generator{TemplatesFileSystem: fsys, TemplatePath: "app", Context: ctx, Options: common.Options{Skeleton: true}}
+	if _, err := g.Render(projectDir); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	files, err := generation.HashFiles(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return projectDir, &generation.Manifest{ProjectName: "demo", Language: "go", Runtime: container.Docker, Files: files}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func actions(changes []Change) map[string]string {
+	got := make(map[string]string, len(changes))
+	for _, change := range changes {
+		got[change.Path] = change.Action
+	}
+	return got
+}
+
+var baseFiles = map[string]string{
+	"template.yaml": templateManifest,
+	"README.md":     "# demo\n",
+	"removed.txt":   "removed\n",
+	"kept.txt":      "kept\n",
+	"deleted.txt":   "deleted\n",
+	"config.yaml":   "port: 8080\n",
+	"main.go":       "package main\n\nfunc one() {}\n\nfunc two() {}\n\nfunc three() {}\n",
+	"conflict.go":   "package main\n\nconst name = \"demo\"\n",
+}
+
+var currentFiles = map[string]string{
+	"template.yaml": templateManifest,
+	"README.md":     "# demo\n",
+	"deleted.txt":   "deleted in a new version\n",
+	"config.yaml":   "port: 9090\n",
+	"main.go":       "package main\n\nfunc first() {}\n\nfunc two() {}\n\nfunc three() {}\n",
+	"conflict.go":   "package main\n\nconst name = \"template\"\n",
+	"added.txt":     "added\n",
+}
+
+// userChanges are the changes made in the project after it was generated.
+var userChanges = map[string]string{
+	"kept.txt":    "kept and changed\n",
+	"main.go":     "package main\n\nfunc one() {}\n\nfunc two() {}\n\nfunc third() {}\n",
+	"conflict.go": "package main\n\nconst name = \"project\"\n",
+}
+
+func TestRun(t *testing.T) {
+	projectDir, recorded := generate(t, templateFS(baseFiles))
+	writeFiles(t, projectDir, userChanges)
+	if err := os.Remove(filepath.Join(projectDir, "deleted.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	u := &Updater{
+		ProjectDir: projectDir,
+		Recorded:   recorded,
+		Base:       &Template{FS: templateFS(baseFiles), Path: "app"},
+		Current:    Template{FS: templateFS(currentFiles), Path: "app"},
+	}
+	changes, err := u.Run()
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	want := map[string]string{
+		"removed.txt": ActionRemoved,
+		"kept.txt":    ActionKept,
+		"deleted.txt": ActionDeletedLocally,
+		"config.yaml": ActionUpdated,
+		"main.go":     ActionMerged,
+		"conflict.go": ActionConflict,
+		"added.txt":   ActionAdded,
+	}
+	got := actions(changes)
+	for path, action := range want {
+		if got[path] != action {
+			t.Errorf("%s: action = %q, want %q", path, got[path], action)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("Run() changes = %+v, want %d", changes, len(want))
+	}
+
+	if _, err := os.Stat(filepath.Join(projectDir, "removed.txt")); !os.IsNotExist(err) {
+		t.Error("removed.txt is still in the project")
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, "deleted.txt")); !os.IsNotExist(err) {
+		t.Error("deleted.txt was restored")
+	}
+	if got := readFile(t, projectDir, "kept.txt"); got != userChanges["kept.txt"] {
+		t.Errorf("kept.txt = %q, want the changes of the user", got)
+	}
+	if got := readFile(t, projectDir, "config.yaml"); got != currentFiles["config.yaml"] {
+		t.Errorf("config.yaml = %q, want the current version", got)
+	}
+	if got, want := readFile(t, projectDir, "main.go"), "package main\n\nfunc first() {}\n\nfunc two() {}\n\nfunc third() {}\n"; got != want {
+		t.Errorf("main.go =\n%s\nwant\n%s", got, want)
+	}
+	if got := readFile(t, projectDir, "conflict.go"); !strings.Contains(got, "<<<<<<<") || !strings.Contains(got, ">>>>>>>") {
+		t.Errorf("conflict.go has no conflict markers:\n%s", got)
+	}
+	if !HasConflicts(changes) {
+		t.Error("HasConflicts() = false, want true")
+	}
+
+	updated, err := generation.Load(projectDir)
+	if err != nil {
+		t.Fatalf("the generation is not recorded: %v", err)
+	}
+	if _, ok := updated.Files["added.txt"]; !ok {
+		t.Error("the recorded files are not the ones of the current version")
+	}
+}
+
+// TestRunWithoutBase updates a project whose original template version is not available,
+// the recorded hashes tell which files the user changed.
+func TestRunWithoutBase(t *testing.T) {
+	projectDir, recorded := generate(t, templateFS(baseFiles))
+	writeFiles(t, projectDir, userChanges)
+
+	u := &Updater{ProjectDir: projectDir, Recorded: recorded, Current: Template{FS: templateFS(currentFiles), Path: "app"}}
+	changes, err := u.Run()
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	got := actions(changes)
+	for path, action := range map[string]string{
+		"removed.txt": ActionRemoved,
+		"config.yaml": ActionUpdated,
+		"main.go":     ActionNewVersion,
+		"conflict.go": ActionNewVersion,
+	} {
+		if got[path] != action {
+			t.Errorf("%s: action = %q, want %q", path, got[path], action)
+		}
+	}
+
+	if got := readFile(t, projectDir, "main.go"); got != userChanges["main.go"] {
+		t.Errorf("main.go = %q, want the changes of the user", got)
+	}
+	if got := readFile(t, projectDir, "main.go"+generator.KeepBothSuffix); got != currentFiles["main.go"] {
+		t.Errorf("main.go%s = %q, want the current version", generator.KeepBothSuffix, got)
+	}
+}
+
+// TestRunWithoutBaseAfterContainers updates a project generated by running the containers of the template.
+// The current version is rendered with the skeleton instead, the files the containers created are left alone.
+func TestRunWithoutBaseAfterContainers(t *testing.T) {
+	const manifest = templateManifest + `containers:
+  - dockerfile: build.Dockerfile
+    image: app-generator:latest
+skeleton:
+  source: skeleton
+`
+	template := map[string]string{
+		"template.yaml":    manifest,
+		"build.Dockerfile": "FROM scratch\n",
+		"skeleton/pom.xml": "<artifactId>skeleton</artifactId>\n",
+	}
+
+	projectDir, recorded := generate(t, templateFS(template))
+	// what the container created, and recorded as generated
+	writeFiles(t, projectDir, map[string]string{"pom.xml": "<artifactId>demo</artifactId>\n<version>1.0</version>\n"})
+	files, err := generation.HashFiles(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded.Files = files
+
+	template["skeleton/pom.xml"] = "<artifactId>skeleton</artifactId>\n<packaging>jar</packaging>\n"
+	u := &Updater{ProjectDir: projectDir, Recorded: recorded, Current: Template{FS: templateFS(template), Path: "app"}}
+	changes, err := u.Run()
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if got := actions(changes)["pom.xml"]; got != ActionKept {
+		t.Errorf("pom.xml: action = %q, want %q", got, ActionKept)
+	}
+	if got := readFile(t, projectDir, "pom.xml"); got != "<artifactId>demo</artifactId>\n<version>1.0</version>\n" {
+		t.Errorf("pom.xml = %q, want the version the container created", got)
+	}
+}
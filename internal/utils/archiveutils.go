@@ -0,0 +1,194 @@
+package utils
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TarDir writes the content of dir as a tar archive to w, the names in the archive are relative to dir.
+func TarDir(dir string, w io.Writer) error {
+	tarWriter := tar.NewWriter(w)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(dir, p)
+		if err != nil || relPath == "." {
+			return err
+		}
+		return addToTar(tarWriter, p, filepath.ToSlash(relPath), d)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to archive %s: %w", dir, err)
+	}
+	return tarWriter.Close()
+}
+
+func addToTar(tarWriter *tar.Writer, p, name string, d fs.DirEntry) error {
+	info, err := d.Info()
+	if err != nil {
+		return err
+	}
+
+	link := ""
+	if info.Mode()&fs.ModeSymlink != 0 {
+		if link, err = os.Readlink(p); err != nil {
+			return err
+		}
+	}
+
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if d.IsDir() {
+		header.Name += "/"
+	}
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+	file, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(tarWriter, file)
+	return err
+}
+
+// TarAndZip writes the content of dir as a tar.gz archive to w.
+func TarAndZip(dir string, w io.Writer) error {
+	gzipWriter := gzip.NewWriter(w)
+	if err := TarDir(dir, gzipWriter); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+// UnzipAndUntar extracts the tar.gz archive into destDir, files keep the permissions they were archived with.
+// Entries and links that would end up outside of destDir fail the extraction, so do entries below a link:
+// a link may point to another link, only the extracted tree tells where it ends up.
+func UnzipAndUntar(archive io.Reader, destDir string) error {
+	gzipReader, err := gzip.NewReader(archive)
+	if err != nil {
+		return fmt.Errorf("failed to create gzip reader: %w", err)
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return checkLinks(destDir)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar entry: %w", err)
+		}
+
+		extractedFilePath := filepath.Join(destDir, header.Name)
+		if !strings.HasPrefix(extractedFilePath, filepath.Clean(destDir)+string(os.PathSeparator)) {
+			return fmt.Errorf("invalid tar entry %s: it points outside of %s", header.Name, destDir)
+		}
+		if err := checkNoLinks(destDir, extractedFilePath); err != nil {
+			return fmt.Errorf("invalid tar entry %s: %w", header.Name, err)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(extractedFilePath, os.ModePerm); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", extractedFilePath, err)
+			}
+		case tar.TypeSymlink:
+			// a file written through a link must stay inside destDir as well
+			target := header.Linkname
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(extractedFilePath), target)
+			}
+			if !strings.HasPrefix(filepath.Clean(target), filepath.Clean(destDir)+string(os.PathSeparator)) {
+				return fmt.Errorf("invalid tar entry %s: its link %s points outside of %s", header.Name, header.Linkname, destDir)
+			}
+			if err := os.Symlink(header.Linkname, extractedFilePath); err != nil {
+				return fmt.Errorf("failed to create symlink %s: %w", extractedFilePath, err)
+			}
+		case tar.TypeReg:
+			if err := extractFile(tarReader, extractedFilePath, header.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// checkNoLinks returns an error if a path between destDir and p (including p) is a link.
+// Nothing is written through a link, no matter where it points.
+func checkNoLinks(destDir, p string) error {
+	rel, err := filepath.Rel(destDir, p)
+	if err != nil {
+		return err
+	}
+	current := destDir
+	for _, name := range strings.Split(rel, string(os.PathSeparator)) {
+		current = filepath.Join(current, name)
+		info, err := os.Lstat(current)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("it goes through the link %s", current)
+		}
+	}
+	return nil
+}
+
+// checkLinks returns an error if a link below destDir resolves to a path outside of it, or does not resolve at all.
+func checkLinks(destDir string) error {
+	root, err := filepath.EvalSymlinks(destDir)
+	if err != nil {
+		return err
+	}
+	return filepath.WalkDir(destDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.Type()&fs.ModeSymlink == 0 {
+			return err
+		}
+		target, err := filepath.EvalSymlinks(p)
+		if err != nil {
+			return fmt.Errorf("invalid link %s: %w", p, err)
+		}
+		if !strings.HasPrefix(target, root+string(os.PathSeparator)) {
+			return fmt.Errorf("invalid link %s: it resolves to %s, outside of %s", p, target, destDir)
+		}
+		return nil
+	})
+}
+
+func extractFile(r io.Reader, filePath string, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(filePath), err)
+	}
+
+	outputFile, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", filePath, err)
+	}
+	defer outputFile.Close()
+
+	if _, err := io.Copy(outputFile, r); err != nil {
+		return fmt.Errorf("failed to extract file %s: %w", filePath, err)
+	}
+	return nil
+}
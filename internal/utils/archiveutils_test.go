@@ -0,0 +1,182 @@
+package utils
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTarAndZipRoundTrip(t *testing.T) {
+	src := t.TempDir()
+	files := map[string]string{
+		"README.md":                 "# demo\n",
+		"src/main/java/App.java":    "class App {}\n",
+		"src/main/resources/.empty": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(src, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(src, "mvnw"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(src, "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("README.md", filepath.Join(src, "LINK.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	var archive bytes.Buffer
+	if err := TarAndZip(src, &archive); err != nil {
+		t.Fatalf("TarAndZip() error = %v", err)
+	}
+
+	dest := t.TempDir()
+	if err := UnzipAndUntar(&archive, dest); err != nil {
+		t.Fatalf("UnzipAndUntar() error = %v", err)
+	}
+
+	for name, want := range files {
+		got, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(name)))
+		if err != nil {
+			t.Errorf("reading %s: %v", name, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	info, err := os.Stat(filepath.Join(dest, "mvnw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("mode of mvnw = %v, want %v", info.Mode().Perm(), os.FileMode(0755))
+	}
+	if info, err := os.Stat(filepath.Join(dest, "empty")); err != nil || !info.IsDir() {
+		t.Errorf("the empty directory was not extracted: %v", err)
+	}
+	if link, err := os.Readlink(filepath.Join(dest, "LINK.md")); err != nil || link != "README.md" {
+		t.Errorf("LINK.md links to %q (%v), want README.md", link, err)
+	}
+}
+
+// archiveOf creates a tar.gz archive with the entries.
+func archiveOf(t *testing.T, headers ...*tar.Header) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for _, header := range headers {
+		if header.Typeflag == tar.TypeReg {
+			header.Size = int64(len("content"))
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			if _, err := tarWriter.Write([]byte("content")); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestUnzipAndUntarRejectsEntriesOutside(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []*tar.Header
+	}{
+		{
+			name:    "parent directory",
+			headers: []*tar.Header{{Name: "../evil.txt", Typeflag: tar.TypeReg, Mode: 0644}},
+		},
+		{
+			name:    "nested parent directory",
+			headers: []*tar.Header{{Name: "src/../../evil.txt", Typeflag: tar.TypeReg, Mode: 0644}},
+		},
+		{
+			name:    "link to a parent directory",
+			headers: []*tar.Header{{Name: "up", Typeflag: tar.TypeSymlink, Linkname: ".."}},
+		},
+		{
+			name: "entry through a link",
+			headers: []*tar.Header{
+				{Name: "sub/", Typeflag: tar.TypeDir, Mode: 0755},
+				{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "sub"},
+				{Name: "link/evil.txt", Typeflag: tar.TypeReg, Mode: 0644},
+			},
+		},
+		{
+			name: "link through a link",
+			headers: []*tar.Header{
+				{Name: "sub/", Typeflag: tar.TypeDir, Mode: 0755},
+				{Name: "d/", Typeflag: tar.TypeDir, Mode: 0755},
+				{Name: "d/l1", Typeflag: tar.TypeSymlink, Linkname: "../sub"},
+				{Name: "d/l1/x", Typeflag: tar.TypeSymlink, Linkname: "../../evil"},
+				{Name: "sub/x/evil.txt", Typeflag: tar.TypeReg, Mode: 0644},
+			},
+		},
+		{
+			name: "link resolving through another link",
+			headers: []*tar.Header{
+				{Name: "sub/", Typeflag: tar.TypeDir, Mode: 0755},
+				{Name: "d/", Typeflag: tar.TypeDir, Mode: 0755},
+				{Name: "d/l1", Typeflag: tar.TypeSymlink, Linkname: "../sub"},
+				{Name: "d/l2", Typeflag: tar.TypeSymlink, Linkname: "l1/../../evil"},
+			},
+		},
+		{
+			name:    "absolute link",
+			headers: []*tar.Header{{Name: "etc", Typeflag: tar.TypeSymlink, Linkname: "/etc"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent := t.TempDir()
+			dest := filepath.Join(parent, "dest")
+			if err := os.Mkdir(dest, 0755); err != nil {
+				t.Fatal(err)
+			}
+			// links to existing directories outside resolve, they must not pass either
+			if err := os.Mkdir(filepath.Join(parent, "evil"), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			err := UnzipAndUntar(archiveOf(t, tt.headers...), dest)
+			if err == nil || !(strings.Contains(err.Error(), "outside of") || strings.Contains(err.Error(), "goes through the link")) {
+				t.Fatalf("UnzipAndUntar() error = %v, want the entry to be rejected", err)
+			}
+			for _, outside := range []string{"evil.txt", "evil/evil.txt"} {
+				if _, err := os.Stat(filepath.Join(parent, outside)); err == nil {
+					t.Errorf("%s was written outside of the destination", outside)
+				}
+			}
+		})
+	}
+}
+
+func TestUnzipAndUntarNotAnArchive(t *testing.T) {
+	if err := UnzipAndUntar(strings.NewReader("not gzip"), t.TempDir()); err == nil {
+		t.Error("UnzipAndUntar() of a broken archive succeeded")
+	}
+}
@@ -1,10 +1,12 @@
 package utils
 
 import (
+	"craft/internal/constants"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 func PrepareProjectDir(projectName string) (string, error) {
@@ -15,40 +17,100 @@ func PrepareProjectDir(projectName string) (string, error) {
 
 	projectDir := filepath.Join(currentPwd, projectName)
 
-	err = os.Mkdir(projectDir, 0755)
+	err = host.Mkdir(projectDir, 0755)
 	if err != nil {
 		return "", fmt.Errorf("could not create project directory: %w", err)
 	}
 
-	fmt.Printf("A new project folder with the name %v was created\n", projectName)
 	return projectDir, nil
 
 }
 
+// PrepareStagingDir creates a hidden staging directory next to the project directory, the project is generated there
+// and only moved into place by CommitStagingDir once every step succeeded.
+// It fails early if the project directory already exists.
+func PrepareStagingDir(projectName string) (stagingDir, projectDir string, err error) {
+	currentPwd, err := os.Getwd()
+	if err != nil {
+		return "", "", fmt.Errorf("could not get current directory: %w", err)
+	}
+
+	projectDir = filepath.Join(currentPwd, projectName)
+	if _, err := host.Stat(projectDir); err == nil {
+		return "", "", fmt.Errorf("could not create project directory: %s already exists", projectDir)
+	}
+
+	// the staging directory is in the same directory, so the final rename is atomic
+	stagingDir, err = host.MkdirTemp(currentPwd, "."+filepath.Base(projectName)+".craft-staging-")
+	if err != nil {
+		return "", "", fmt.Errorf("could not create staging directory: %w", err)
+	}
+
+	if err := host.Chmod(stagingDir, 0755); err != nil {
+		return "", "", fmt.Errorf("could not set permissions of staging directory: %w", err)
+	}
+	return stagingDir, projectDir, nil
+}
+
+// PrepareTempStagingDir creates a staging directory in the temporary directory of the system,
+// for generations whose result is copied (not moved) into place.
+func PrepareTempStagingDir() (string, error) {
+	stagingDir, err := host.MkdirTemp("", constants.ToolName+"-staging-")
+	if err != nil {
+		return "", fmt.Errorf("could not create staging directory: %w", err)
+	}
+	return stagingDir, nil
+}
+
+// CommitStagingDir moves the staging directory to the project directory.
+func CommitStagingDir(stagingDir, projectDir string) error {
+	// rename replaces an empty directory, so a project directory created in the meantime is checked again
+	if _, err := host.Stat(projectDir); err == nil {
+		return fmt.Errorf("could not create project directory: %s already exists", projectDir)
+	}
+
+	if err := host.Rename(stagingDir, projectDir); err != nil {
+		return fmt.Errorf("could not move the staging directory to %s: %w", projectDir, err)
+	}
+	return nil
+}
+
 func CopyDirFromFS(fsys fs.FS, sourceDir, destDir string) error {
+	return CopyDirFromFSExcluding(fsys, sourceDir, destDir, nil)
+}
+
+// CopyDirFromFSExcluding works like CopyDirFromFS but skips the given files and directories (paths relative to sourceDir).
+func CopyDirFromFSExcluding(fsys fs.FS, sourceDir, destDir string, excluded []string) error {
 	err := fs.WalkDir(fsys, sourceDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
-			fmt.Printf("error walking directory: %w", err)
+			fmt.Printf("error walking directory: %v\n", err)
 			return err
 		}
 
 		realPath, err := filepath.Rel(sourceDir, path)
 		if err != nil {
-			fmt.Errorf("error calculating relative path: %w", err)
+			fmt.Printf("error calculating relative path: %v\n", err)
 			return err
 		}
 		targetPath := filepath.Join(destDir, realPath)
 
+		if Contains(excluded, realPath) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
 		if d.IsDir() {
-			err = os.MkdirAll(targetPath, directoryPermissions)
+			err = host.MkdirAll(targetPath, directoryPermissions)
 			if err != nil {
-				fmt.Errorf("error creating directory %q: %w", targetPath, err)
+				fmt.Printf("error creating directory %q: %v\n", targetPath, err)
 				return err
 			}
 		} else {
 			err = CopyFileFromFS(fsys, path, targetPath)
 			if err != nil {
-				fmt.Errorf("error copying file: %w", err)
+				fmt.Printf("error copying file: %v\n", err)
 				return err
 			}
 		}
@@ -63,13 +125,13 @@ func CopyDirFromFS(fsys fs.FS, sourceDir, destDir string) error {
 
 // CopyDirIntoDir copies a source directory into a destination directory
 func CopyDirIntoDir(sourceDir, destinationDir string) error {
-	sourceEntries, err := os.ReadDir(sourceDir)
+	sourceEntries, err := host.ReadDir(sourceDir)
 	if err != nil {
 		return fmt.Errorf("error reading directory %s: %w", sourceDir, err)
 	}
 
 	destinationPath := filepath.Join(destinationDir, filepath.Base(sourceDir))
-	if err := os.MkdirAll(destinationPath, os.ModePerm); err != nil {
+	if err := host.MkdirAll(destinationPath, os.ModePerm); err != nil {
 		return fmt.Errorf("error creating directory %s: %w", destinationPath, err)
 	}
 
@@ -120,10 +182,35 @@ func CopyAllOnePathUpAndRemoveDir(dirPath string) error {
 		return err
 	}
 
-	err = os.RemoveAll(dirPath)
+	err = host.RemoveAll(dirPath)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// ContainedPath joins root and the relative path rel. It fails if rel is absolute or leaves root (e.g. ../sibling):
+// the paths of template manifests, and the values of --set rendered into them, must never point outside the project.
+func ContainedPath(root, rel string) (string, error) {
+	if filepath.IsAbs(rel) || strings.HasPrefix(rel, "/") || strings.HasPrefix(rel, "\\") {
+		return "", fmt.Errorf("the path '%s' has to be relative", rel)
+	}
+	cleaned := filepath.Clean(filepath.FromSlash(rel))
+	if cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("the path '%s' points outside of the project", rel)
+	}
+	return filepath.Join(root, cleaned), nil
+}
+
+// MovePath moves the file or directory sourcePath to targetPath and creates the missing parent directories of targetPath.
+// It fails if targetPath already exists.
+func MovePath(sourcePath, targetPath string) error {
+	if FileExists(targetPath) {
+		return fmt.Errorf("%s already exists", targetPath)
+	}
+	if err := host.MkdirAll(filepath.Dir(targetPath), directoryPermissions); err != nil {
+		return fmt.Errorf("error creating directory %s: %w", filepath.Dir(targetPath), err)
+	}
+	return host.Rename(sourcePath, targetPath)
+}
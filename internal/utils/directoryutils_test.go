@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestContainedPath(t *testing.T) {
+	root := t.TempDir()
+
+	tests := []struct {
+		rel     string
+		want    string
+		wantErr bool
+	}{
+		{rel: "README.md", want: "README.md"},
+		{rel: "src/main/../App.java", want: "src/App.java"},
+		{rel: "./build.Dockerfile", want: "build.Dockerfile"},
+		{rel: "..", wantErr: true},
+		{rel: "../sibling/file", wantErr: true},
+		{rel: "src/../../sibling", wantErr: true},
+		{rel: "/etc/passwd", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ContainedPath(root, tt.rel)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ContainedPath(%q) = %q, want an error", tt.rel, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ContainedPath(%q) error = %v", tt.rel, err)
+		} else if want := filepath.Join(root, filepath.FromSlash(tt.want)); got != want {
+			t.Errorf("ContainedPath(%q) = %q, want %q", tt.rel, got, want)
+		}
+	}
+}
@@ -2,8 +2,8 @@ package utils
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
-	"io"
 	"io/fs"
 	"os"
 	"os/exec"
@@ -27,13 +27,12 @@ const (
 // It takes the fileName, placeholder, replacementWord, and a flag replaceAll (true to replace all occurrences, false for just the first).
 func ChangeWordInFile(fileName, placeholder, replacementWord string, replaceAll bool) error {
 
-	file, err := os.Open(fileName)
+	content, err := host.ReadFile(fileName)
 	if err != nil {
 		return fmt.Errorf("error opening file: %w", err)
 	}
-	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
 	var lines []string
 
 	var replaced bool = false
@@ -57,24 +56,72 @@ func ChangeWordInFile(fileName, placeholder, replacementWord string, replaceAll
 		return fmt.Errorf("error reading file: %w", err)
 	}
 
-	// Open the file for writing only (truncate mode)
-	outputFile, err := os.OpenFile(fileName, os.O_WRONLY|os.O_TRUNC, 0644)
+	var output bytes.Buffer
+	for _, line := range lines {
+		output.WriteString(line + "\n")
+	}
+
+	if err := host.WriteFile(fileName, output.Bytes(), 0644); err != nil {
+		return fmt.Errorf("error writing to file: %w", err)
+	}
+
+	return nil
+}
+
+// ReplaceInDir replaces every occurrence of word with replacement in the names of the files and directories
+// below dir and in the content of its text files. Binary files (containing a NUL byte) are left as they are.
+func ReplaceInDir(dir, word, replacement string) error {
+	entries, err := host.ReadDir(dir)
 	if err != nil {
-		return fmt.Errorf("error opening file for writing: %w", err)
+		return fmt.Errorf("error reading directory %s: %w", dir, err)
 	}
-	defer outputFile.Close()
 
-	writer := bufio.NewWriter(outputFile)
-	for _, line := range lines {
-		if _, err := writer.WriteString(line + "\n"); err != nil {
-			return fmt.Errorf("error writing to file: %w", err)
+	for _, entry := range entries {
+		entryPath := filepath.Join(dir, entry.Name())
+
+		if entry.IsDir() {
+			if err := ReplaceInDir(entryPath, word, replacement); err != nil {
+				return err
+			}
+		} else if entry.Type().IsRegular() {
+			content, err := host.ReadFile(entryPath)
+			if err != nil {
+				return fmt.Errorf("error reading file %s: %w", entryPath, err)
+			}
+			if bytes.Contains(content, []byte(word)) && !bytes.Contains(content, []byte{0}) {
+				// the file exists, its permissions are kept
+				if err := host.WriteFile(entryPath, bytes.ReplaceAll(content, []byte(word), []byte(replacement)), filePermissions); err != nil {
+					return fmt.Errorf("error writing file %s: %w", entryPath, err)
+				}
+			}
+		}
+
+		if strings.Contains(entry.Name(), word) {
+			renamed := filepath.Join(dir, strings.ReplaceAll(entry.Name(), word, replacement))
+			if err := host.Rename(entryPath, renamed); err != nil {
+				return fmt.Errorf("error renaming %s: %w", entryPath, err)
+			}
 		}
 	}
+	return nil
+}
+
+// WriteFile writes data to filePath, creating missing parent directories.
+// Shell scripts are made executable, just like in CopyFileFromFS.
+func WriteFile(filePath string, data []byte) error {
+	if err := host.MkdirAll(filepath.Dir(filePath), directoryPermissions); err != nil {
+		return fmt.Errorf("error creating directories for %s: %w", filePath, err)
+	}
 
-	if err := writer.Flush(); err != nil {
-		return fmt.Errorf("error flushing writer: %w", err)
+	if err := host.WriteFile(filePath, data, filePermissions); err != nil {
+		return fmt.Errorf("error writing file %s: %w", filePath, err)
 	}
 
+	if strings.HasSuffix(filePath, ".sh") {
+		if err := host.Chmod(filePath, binaryPermissoins); err != nil {
+			return fmt.Errorf("error setting permissions for %s: %w", filePath, err)
+		}
+	}
 	return nil
 }
 
@@ -83,7 +130,7 @@ func ChangeWordInFile(fileName, placeholder, replacementWord string, replaceAll
 //-----------------------------------------------------------------------
 
 func RemoveFileFromHost(filePath string) error {
-	err := os.RemoveAll(filePath)
+	err := host.RemoveAll(filePath)
 	if err != nil {
 		return err
 	}
@@ -94,6 +141,42 @@ func RemoveFileFromHost(filePath string) error {
 // Getting things
 //-----------------------------------------------------------------------
 
+// ReadFile reads a file from the host filesystem (or what a recorded dry run would have written there)
+func ReadFile(filePath string) ([]byte, error) {
+	return host.ReadFile(filePath)
+}
+
+// FileExists reports whether a file or directory exists at filePath
+func FileExists(filePath string) bool {
+	_, err := host.Stat(filePath)
+	return err == nil
+}
+
+// ListFilesRecursive returns the paths of all files below dir, relative to dir and sorted
+func ListFilesRecursive(dir string) ([]string, error) {
+	entries, err := host.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading directory %s: %w", dir, err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			files = append(files, entry.Name())
+			continue
+		}
+
+		children, err := ListFilesRecursive(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, child := range children {
+			files = append(files, filepath.Join(entry.Name(), child))
+		}
+	}
+	return files, nil
+}
+
 // GetAllEntries retrieves all entries (files and directories) from a given directory on the host filesystem
 func GetAllEntries(dir string) ([]string, error) {
 	entries, err := os.ReadDir(dir)
@@ -184,12 +267,12 @@ func ListFilesWithPattern(fsys fs.FS, dir string, pattern string) ([]string, err
 
 // CopyAllEntries copies all entries (files and directories) from a source directory to a destination directory
 func CopyAllEntries(sourceDir, destinationDir string) error {
-	entries, err := os.ReadDir(sourceDir)
+	entries, err := host.ReadDir(sourceDir)
 	if err != nil {
 		return fmt.Errorf("error reading directory %s: %w", sourceDir, err)
 	}
 
-	if err := os.MkdirAll(destinationDir, os.ModePerm); err != nil {
+	if err := host.MkdirAll(destinationDir, os.ModePerm); err != nil {
 		return fmt.Errorf("error creating directory %s: %w", destinationDir, err)
 	}
 
@@ -216,34 +299,26 @@ func CopyAllEntries(sourceDir, destinationDir string) error {
 func CopyFileFromFS(sourceFS fs.FS, sourcePath string, destPath string) error {
 	// fmt.Printf("copy file from fs -> from %v to %v\n", sourcePath, destPath)
 
-	sourceFile, err := sourceFS.Open(sourcePath)
+	data, err := fs.ReadFile(sourceFS, sourcePath)
 	if err != nil {
-		fmt.Printf("failed to open source file %q: %w\n", sourcePath, err)
+		fmt.Printf("failed to read source file %q: %v\n", sourcePath, err)
 		return err
 	}
-	defer sourceFile.Close()
 
-	err = os.MkdirAll(filepath.Dir(destPath), filePermissions)
+	err = host.MkdirAll(filepath.Dir(destPath), directoryPermissions)
 	if err != nil {
-		fmt.Printf("failed to create directories for %q: %w\n", destPath, err)
+		fmt.Printf("failed to create directories for %q: %v\n", destPath, err)
 		return err
 	}
 
-	destFile, err := os.Create(destPath)
+	err = host.WriteFile(destPath, data, filePermissions)
 	if err != nil {
-		fmt.Printf("failed to create destination file %q: %w\n", destPath, err)
-		return err
-	}
-	defer destFile.Close()
-
-	_, err = io.Copy(destFile, sourceFile)
-	if err != nil {
-		fmt.Errorf("failed to copy content from %q to %q: %w\n", sourcePath, destPath, err)
+		fmt.Printf("failed to write destination file %q: %v\n", destPath, err)
 		return err
 	}
 
 	if strings.HasSuffix(destPath, ".sh") {
-		err = os.Chmod(destPath, binaryPermissoins)
+		err = host.Chmod(destPath, binaryPermissoins)
 		if err != nil {
 			fmt.Printf("failed to set permissions for %q: %v\n", destPath, err)
 			return err
@@ -253,22 +328,24 @@ func CopyFileFromFS(sourceFS fs.FS, sourcePath string, destPath string) error {
 	return nil
 }
 
-// CopyFile copies a file from the source path to the destination path
+// CopyFile copies a file from the source path to the destination path, keeping its permissions.
+// Missing parent directories of the destination are created.
 func CopyFile(sourcePath, destinationPath string) error {
-	source, err := os.Open(sourcePath)
+	info, err := host.Stat(sourcePath)
 	if err != nil {
 		return fmt.Errorf("error opening source file %s: %w", sourcePath, err)
 	}
-	defer source.Close()
 
-	destination, err := os.Create(destinationPath)
-	if err != nil {
-		return fmt.Errorf("error creating destination file %s: %w", destinationPath, err)
+	if err := host.MkdirAll(filepath.Dir(destinationPath), directoryPermissions); err != nil {
+		return fmt.Errorf("error creating directories for %s: %w", destinationPath, err)
 	}
-	defer destination.Close()
 
-	_, err = io.Copy(destination, source)
+	data, err := host.ReadFile(sourcePath)
 	if err != nil {
+		return fmt.Errorf("error reading source file %s: %w", sourcePath, err)
+	}
+
+	if err := host.WriteFile(destinationPath, data, info.Mode().Perm()); err != nil {
 		return fmt.Errorf("error copying file from %s to %s: %w", sourcePath, destinationPath, err)
 	}
 
@@ -279,9 +356,9 @@ func CopyFile(sourcePath, destinationPath string) error {
 func RenameFilesWithPrefix(filePaths []string, projectHostDir, prefix, newPrefix string) error {
 	for _, filePath := range filePaths {
 		hostFilePath := path.Join(projectHostDir, filePath)
-		renamedFilePath := strings.ReplaceAll(hostFilePath, prefix, newPrefix)
+		renamedFilePath := path.Join(projectHostDir, strings.Replace(filePath, prefix, newPrefix, 1))
 
-		if err := os.Rename(hostFilePath, renamedFilePath); err != nil {
+		if err := host.Rename(hostFilePath, renamedFilePath); err != nil {
 			return fmt.Errorf("error renaming file %v to replace prefix %v with %v: %v", hostFilePath, prefix, newPrefix, err)
 		}
 	}
@@ -294,7 +371,7 @@ func TrimFileSuffix(filePaths []string, projectHostDir, suffix string) error {
 		hostFilePath := path.Join(projectHostDir, filePath)
 		cleanedFilePath := strings.TrimSuffix(hostFilePath, suffix)
 
-		if err := os.Rename(hostFilePath, cleanedFilePath); err != nil {
+		if err := host.Rename(hostFilePath, cleanedFilePath); err != nil {
 			return fmt.Errorf("error removing suffix %v in %v: %v", suffix, hostFilePath, err)
 		}
 	}
@@ -310,7 +387,7 @@ func TrimFileSuffix(filePaths []string, projectHostDir, suffix string) error {
 //	args: Arguments to pass to the script
 func ExecuteScript(scriptPath, workingDir string, args ...string) error {
 	// Set execute permissions on the script
-	if err := os.Chmod(scriptPath, 0771); err != nil {
+	if err := host.Chmod(scriptPath, binaryPermissoins); err != nil {
 		return fmt.Errorf("error setting execute permissions on script: %v", err)
 	}
 
@@ -321,7 +398,7 @@ func ExecuteScript(scriptPath, workingDir string, args ...string) error {
 	execCmd.Dir = workingDir
 
 	// Run the command
-	if err := execCmd.Run(); err != nil {
+	if err := host.Run(execCmd); err != nil {
 		return fmt.Errorf("error executing script: %v", err)
 	}
 	return nil
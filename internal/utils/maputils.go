@@ -1,5 +1,7 @@
 package utils
 
+import "sort"
+
 /*
 Get keys of a map as array
 */
@@ -13,6 +15,15 @@ func Keys[T any](dict map[string]T) []string {
 	return keys
 }
 
+/*
+Get keys of a map as sorted array
+*/
+func SortedKeys[T any](dict map[string]T) []string {
+	keys := Keys(dict)
+	sort.Strings(keys)
+	return keys
+}
+
 /*
 Get values of a map as array
 */
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Operator performs every change craft makes to the host: file system changes and script executions.
+// All helpers in this package go through the active operator, which is the real file system by default.
+// A Recorder can be set instead to only record what would be done (craft new --dry-run).
+type Operator interface {
+	Mkdir(path string, perm os.FileMode) error
+	MkdirAll(path string, perm os.FileMode) error
+	MkdirTemp(dir, pattern string) (string, error)
+	WriteFile(path string, data []byte, perm os.FileMode) error
+	Rename(oldPath, newPath string) error
+	RemoveAll(path string) error
+	Chmod(path string, mode os.FileMode) error
+	Run(cmd *exec.Cmd) error
+
+	ReadFile(path string) ([]byte, error)
+	ReadDir(path string) ([]os.DirEntry, error)
+	Stat(path string) (os.FileInfo, error)
+}
+
+var host Operator = osOperator{}
+
+// SetOperator replaces the active operator and returns a function that restores the previous one.
+func SetOperator(operator Operator) (restore func()) {
+	previous := host
+	host = operator
+	return func() { host = previous }
+}
+
+// ActiveRecorder returns the active operator if it is a Recorder.
+// Steps that do not go through the operator (e.g. talking to the docker daemon) record themselves with it.
+func ActiveRecorder() (*Recorder, bool) {
+	recorder, ok := host.(*Recorder)
+	return recorder, ok
+}
+
+// osOperator works on the real file system.
+type osOperator struct{}
+
+func (osOperator) Mkdir(path string, perm os.FileMode) error    { return os.Mkdir(path, perm) }
+func (osOperator) MkdirAll(path string, perm os.FileMode) error { return os.MkdirAll(path, perm) }
+func (osOperator) MkdirTemp(dir, pattern string) (string, error) {
+	return os.MkdirTemp(dir, pattern)
+}
+func (osOperator) WriteFile(path string, data []byte, perm os.FileMode) error {
+	return os.WriteFile(path, data, perm)
+}
+func (osOperator) Rename(oldPath, newPath string) error       { return os.Rename(oldPath, newPath) }
+func (osOperator) RemoveAll(path string) error                { return os.RemoveAll(path) }
+func (osOperator) Chmod(path string, mode os.FileMode) error  { return os.Chmod(path, mode) }
+func (osOperator) Run(cmd *exec.Cmd) error                    { return cmd.Run() }
+func (osOperator) ReadFile(path string) ([]byte, error)       { return os.ReadFile(path) }
+func (osOperator) ReadDir(path string) ([]os.DirEntry, error) { return os.ReadDir(path) }
+func (osOperator) Stat(path string) (os.FileInfo, error)      { return os.Stat(path) }
@@ -0,0 +1,305 @@
+package utils
+
+import (
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kinds of recorded operations.
+const (
+	OperationMkdir  = "mkdir"
+	OperationWrite  = "write"
+	OperationRename = "rename"
+	OperationRemove = "remove"
+	OperationChmod  = "chmod"
+	OperationRun    = "run"
+	// OperationContainer is a generator image that is built and run, Target is the image.
+	OperationContainer = "container"
+)
+
+// Operation is a change the Recorder did not perform.
+type Operation struct {
+	Kind string
+	Path string
+	// Target is the new path of a rename.
+	Target string
+	// Args and Dir are set for executed commands.
+	Args []string
+	Dir  string
+}
+
+// Recorder is an Operator that records all changes instead of performing them.
+// It keeps a virtual view of the recorded changes on top of the real file system,
+// so later steps can read what earlier (recorded) steps would have written.
+//
+// The output of executed scripts is unknown: once a script was recorded,
+// reading a file or directory that does not exist returns empty content instead of an error.
+type Recorder struct {
+	operations []Operation
+	created    map[string]*virtualEntry
+	removed    map[string]bool
+	scriptsRun bool
+}
+
+type virtualEntry struct {
+	dir  bool
+	data []byte
+	mode os.FileMode
+	// source is set for real files that were renamed, their content is read lazily.
+	source string
+}
+
+// NewRecorder creates an empty recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{
+		created: make(map[string]*virtualEntry),
+		removed: make(map[string]bool),
+	}
+}
+
+// Operations returns all recorded operations in the order they were requested.
+func (r *Recorder) Operations() []Operation {
+	return r.operations
+}
+
+// Tree returns the paths (relative to root) that would exist below root after all recorded operations, sorted.
+// Directories end with a slash.
+func (r *Recorder) Tree(root string) []string {
+	var paths []string
+	for p, entry := range r.created {
+		rel, err := filepath.Rel(root, p)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if entry.dir {
+			rel += "/"
+		}
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (r *Recorder) record(operation Operation) {
+	r.operations = append(r.operations, operation)
+}
+
+func (r *Recorder) isRemoved(p string) bool {
+	for current := p; ; current = filepath.Dir(current) {
+		if r.removed[current] {
+			return true
+		}
+		if _, ok := r.created[current]; ok && current != p {
+			return false
+		}
+		if parent := filepath.Dir(current); parent == current {
+			return false
+		}
+	}
+}
+
+func (r *Recorder) lookup(p string) (os.FileInfo, *virtualEntry, error) {
+	p = filepath.Clean(p)
+	if entry, ok := r.created[p]; ok {
+		return virtualInfo{name: filepath.Base(p), entry: entry}, entry, nil
+	}
+	if r.isRemoved(p) {
+		return nil, nil, &fs.PathError{Op: "stat", Path: p, Err: fs.ErrNotExist}
+	}
+	info, err := os.Stat(r.realPath(p))
+	return info, nil, err
+}
+
+// realPath returns the path on disk that p stands for: the path below a real directory renamed to an ancestor of p,
+// or p itself.
+func (r *Recorder) realPath(p string) string {
+	for current := p; ; current = filepath.Dir(current) {
+		if entry, ok := r.created[current]; ok {
+			if entry.source == "" {
+				return p
+			}
+			return entry.source + strings.TrimPrefix(p, current)
+		}
+		if parent := filepath.Dir(current); parent == current {
+			return p
+		}
+	}
+}
+
+func (r *Recorder) Mkdir(path string, perm os.FileMode) error {
+	if _, _, err := r.lookup(path); err == nil {
+		return &fs.PathError{Op: "mkdir", Path: path, Err: fs.ErrExist}
+	}
+	r.record(Operation{Kind: OperationMkdir, Path: path})
+	r.created[filepath.Clean(path)] = &virtualEntry{dir: true, mode: fs.ModeDir | perm}
+	return nil
+}
+
+func (r *Recorder) MkdirAll(path string, perm os.FileMode) error {
+	path = filepath.Clean(path)
+	if info, _, err := r.lookup(path); err == nil && info.IsDir() {
+		return nil
+	}
+	if parent := filepath.Dir(path); parent != path {
+		if err := r.MkdirAll(parent, perm); err != nil {
+			return err
+		}
+	}
+	r.record(Operation{Kind: OperationMkdir, Path: path})
+	r.created[path] = &virtualEntry{dir: true, mode: fs.ModeDir | perm}
+	return nil
+}
+
+// MkdirTemp records a directory named after the pattern, with "dry-run" in place of the random part.
+func (r *Recorder) MkdirTemp(dir, pattern string) (string, error) {
+	if !strings.Contains(pattern, "*") {
+		pattern += "*"
+	}
+	path := filepath.Join(dir, strings.Replace(pattern, "*", "dry-run", 1))
+	if err := r.Mkdir(path, 0700); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func (r *Recorder) WriteFile(path string, data []byte, perm os.FileMode) error {
+	r.record(Operation{Kind: OperationWrite, Path: path})
+	r.created[filepath.Clean(path)] = &virtualEntry{data: data, mode: perm}
+	return nil
+}
+
+func (r *Recorder) Rename(oldPath, newPath string) error {
+	oldPath, newPath = filepath.Clean(oldPath), filepath.Clean(newPath)
+	info, entry, err := r.lookup(oldPath)
+	if err != nil && !r.scriptsRun {
+		return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: fs.ErrNotExist}
+	}
+	r.record(Operation{Kind: OperationRename, Path: oldPath, Target: newPath})
+
+	// recorded changes below a real directory move along with it
+	moved := make(map[string]*virtualEntry)
+	for p, child := range r.created {
+		if p == oldPath || strings.HasPrefix(p, oldPath+string(filepath.Separator)) {
+			delete(r.created, p)
+			moved[newPath+strings.TrimPrefix(p, oldPath)] = child
+		}
+	}
+	for p, child := range moved {
+		r.created[p] = child
+	}
+
+	switch {
+	case entry != nil:
+	case info != nil:
+		r.created[newPath] = &virtualEntry{dir: info.IsDir(), mode: info.Mode(), source: r.realPath(oldPath)}
+	default:
+		r.created[newPath] = &virtualEntry{mode: filePermissions}
+	}
+	r.removed[oldPath] = true
+	delete(r.removed, newPath)
+	return nil
+}
+
+func (r *Recorder) RemoveAll(path string) error {
+	path = filepath.Clean(path)
+	r.record(Operation{Kind: OperationRemove, Path: path})
+	for p := range r.created {
+		if p == path || strings.HasPrefix(p, path+string(filepath.Separator)) {
+			delete(r.created, p)
+		}
+	}
+	r.removed[path] = true
+	return nil
+}
+
+func (r *Recorder) Chmod(path string, mode os.FileMode) error {
+	r.record(Operation{Kind: OperationChmod, Path: path})
+	if entry, ok := r.created[filepath.Clean(path)]; ok {
+		entry.mode = mode
+	}
+	return nil
+}
+
+func (r *Recorder) Run(cmd *exec.Cmd) error {
+	r.record(Operation{Kind: OperationRun, Path: cmd.Path, Args: cmd.Args[1:], Dir: cmd.Dir})
+	r.scriptsRun = true
+	return nil
+}
+
+// RecordContainer records the build of image from dockerfile and the run of command in it inside dir.
+// Like a script, the container creates files that are not known before it ran.
+func (r *Recorder) RecordContainer(dockerfile, image string, command []string, dir string) {
+	r.record(Operation{Kind: OperationContainer, Path: dockerfile, Target: image, Args: command, Dir: dir})
+	r.scriptsRun = true
+}
+
+func (r *Recorder) ReadFile(path string) ([]byte, error) {
+	_, entry, err := r.lookup(path)
+	switch {
+	case entry != nil && entry.source != "":
+		return os.ReadFile(entry.source)
+	case entry != nil:
+		return entry.data, nil
+	case err == nil:
+		return os.ReadFile(r.realPath(filepath.Clean(path)))
+	case r.scriptsRun:
+		return []byte{}, nil
+	default:
+		return nil, err
+	}
+}
+
+func (r *Recorder) ReadDir(path string) ([]os.DirEntry, error) {
+	path = filepath.Clean(path)
+	merged := make(map[string]os.DirEntry)
+
+	realEntries, realErr := os.ReadDir(r.realPath(path))
+	if realErr == nil && !r.isRemoved(path) {
+		for _, entry := range realEntries {
+			if !r.removed[filepath.Join(path, entry.Name())] {
+				merged[entry.Name()] = entry
+			}
+		}
+	}
+
+	_, isCreated := r.created[path]
+	for p, entry := range r.created {
+		if filepath.Dir(p) == path && p != path {
+			merged[filepath.Base(p)] = fs.FileInfoToDirEntry(virtualInfo{name: filepath.Base(p), entry: entry})
+		}
+	}
+
+	if len(merged) == 0 && !isCreated && (realErr != nil || r.isRemoved(path)) && !r.scriptsRun {
+		return nil, &fs.PathError{Op: "readdir", Path: path, Err: fs.ErrNotExist}
+	}
+
+	entries := make([]os.DirEntry, 0, len(merged))
+	for _, entry := range merged {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func (r *Recorder) Stat(path string) (os.FileInfo, error) {
+	info, _, err := r.lookup(path)
+	return info, err
+}
+
+// virtualInfo describes a file or directory that only exists in the recorder.
+type virtualInfo struct {
+	name  string
+	entry *virtualEntry
+}
+
+func (i virtualInfo) Name() string       { return i.name }
+func (i virtualInfo) Size() int64        { return int64(len(i.entry.data)) }
+func (i virtualInfo) Mode() fs.FileMode  { return i.entry.mode }
+func (i virtualInfo) ModTime() time.Time { return time.Time{} }
+func (i virtualInfo) IsDir() bool        { return i.entry.dir }
+func (i virtualInfo) Sys() any           { return nil }
@@ -0,0 +1,219 @@
+package utils
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// snapshot returns the paths below dir with the content of their files, directories end with a slash.
+func snapshot(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		if d.IsDir() {
+			files[rel+"/"] = ""
+			return nil
+		}
+		content, err := os.ReadFile(p)
+		files[rel] = string(content)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// newProject creates a directory with a few files of a generated project.
+func newProject(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"README.md":                       "# craft-placeholder\n",
+		"craft-placeholder/pom.xml":       "<artifactId>craft-placeholder</artifactId>\n",
+		"src/main/java/PACKAGE/App.java":  "package PACKAGE;\n",
+		"build.Dockerfile":                "FROM scratch\n",
+		"craft-placeholder/.dockerignore": "target\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// record runs fn with a recorder as operator and checks that the directory was not touched.
+func record(t *testing.T, dir string, fn func(r *Recorder)) *Recorder {
+	t.Helper()
+	before := snapshot(t, dir)
+
+	recorder := NewRecorder()
+	restore := SetOperator(recorder)
+	fn(recorder)
+	restore()
+
+	after := snapshot(t, dir)
+	for p, content := range before {
+		if got, ok := after[p]; !ok || got != content {
+			t.Errorf("%s was changed on disk during the dry run", p)
+		}
+	}
+	for p := range after {
+		if _, ok := before[p]; !ok {
+			t.Errorf("%s was created on disk during the dry run", p)
+		}
+	}
+	return recorder
+}
+
+func hasOperation(operations []Operation, want Operation) bool {
+	return slices.ContainsFunc(operations, func(op Operation) bool {
+		return op.Kind == want.Kind && op.Path == want.Path && op.Target == want.Target
+	})
+}
+
+func TestRecorderMovePath(t *testing.T) {
+	dir := newProject(t)
+	source := filepath.Join(dir, "src/main/java/PACKAGE")
+	target := filepath.Join(dir, "src/main/java/com/example")
+
+	recorder := record(t, dir, func(r *Recorder) {
+		if err := MovePath(source, target); err != nil {
+			t.Fatalf("MovePath() error = %v", err)
+		}
+		if FileExists(source) {
+			t.Error("the source still exists in the recorded view")
+		}
+		if !FileExists(target) {
+			t.Error("the target does not exist in the recorded view")
+		}
+		content, err := ReadFile(filepath.Join(target, "App.java"))
+		if err != nil || string(content) != "package PACKAGE;\n" {
+			t.Errorf("ReadFile() of the moved file = %q, %v", content, err)
+		}
+		if err := MovePath(source, target); err == nil {
+			t.Error("MovePath() onto an existing target succeeded")
+		}
+	})
+
+	operations := recorder.Operations()
+	if !hasOperation(operations, Operation{Kind: OperationMkdir, Path: filepath.Join(dir, "src/main/java/com")}) {
+		t.Errorf("the parent of the target is not created: %+v", operations)
+	}
+	if !hasOperation(operations, Operation{Kind: OperationRename, Path: source, Target: target}) {
+		t.Errorf("the move is not recorded: %+v", operations)
+	}
+}
+
+func TestRecorderReplaceInDir(t *testing.T) {
+	dir := newProject(t)
+
+	recorder := record(t, dir, func(r *Recorder) {
+		if err := ReplaceInDir(dir, "craft-placeholder", "orders"); err != nil {
+			t.Fatalf("ReplaceInDir() error = %v", err)
+		}
+		content, err := ReadFile(filepath.Join(dir, "orders/pom.xml"))
+		if err != nil || string(content) != "<artifactId>orders</artifactId>\n" {
+			t.Errorf("ReadFile() of the replaced file = %q, %v", content, err)
+		}
+	})
+
+	operations := recorder.Operations()
+	for _, want := range []Operation{
+		{Kind: OperationWrite, Path: filepath.Join(dir, "README.md")},
+		{Kind: OperationWrite, Path: filepath.Join(dir, "craft-placeholder/pom.xml")},
+		{Kind: OperationRename, Path: filepath.Join(dir, "craft-placeholder"), Target: filepath.Join(dir, "orders")},
+	} {
+		if !hasOperation(operations, want) {
+			t.Errorf("%+v is not recorded: %+v", want, operations)
+		}
+	}
+	if hasOperation(operations, Operation{Kind: OperationWrite, Path: filepath.Join(dir, "build.Dockerfile")}) {
+		t.Error("a file without the word is rewritten")
+	}
+}
+
+func TestRecorderWriteFile(t *testing.T) {
+	dir := newProject(t)
+	script := filepath.Join(dir, "scripts/setup.sh")
+
+	recorder := record(t, dir, func(r *Recorder) {
+		if err := WriteFile(script, []byte("#!/bin/sh\n")); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		if err := WriteFile(filepath.Join(dir, "README.md"), []byte("# orders\n")); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		content, err := ReadFile(filepath.Join(dir, "README.md"))
+		if err != nil || string(content) != "# orders\n" {
+			t.Errorf("ReadFile() of the written file = %q, %v", content, err)
+		}
+	})
+
+	operations := recorder.Operations()
+	for _, want := range []Operation{
+		{Kind: OperationMkdir, Path: filepath.Join(dir, "scripts")},
+		{Kind: OperationWrite, Path: script},
+		{Kind: OperationChmod, Path: script},
+		{Kind: OperationWrite, Path: filepath.Join(dir, "README.md")},
+	} {
+		if !hasOperation(operations, want) {
+			t.Errorf("%+v is not recorded: %+v", want, operations)
+		}
+	}
+	if got, want := recorder.Tree(dir), []string{"scripts/", "scripts/setup.sh", "README.md"}; !slices.Equal(sorted(got), sorted(want)) {
+		t.Errorf("Tree() = %q, want %q", got, want)
+	}
+}
+
+func TestRecorderRemove(t *testing.T) {
+	dir := newProject(t)
+
+	recorder := record(t, dir, func(r *Recorder) {
+		if err := RemoveFileFromHost(filepath.Join(dir, "build.Dockerfile")); err != nil {
+			t.Fatalf("RemoveFileFromHost() error = %v", err)
+		}
+		if FileExists(filepath.Join(dir, "build.Dockerfile")) {
+			t.Error("the removed file still exists in the recorded view")
+		}
+
+		if err := CopyAllOnePathUpAndRemoveDir(filepath.Join(dir, "craft-placeholder")); err != nil {
+			t.Fatalf("CopyAllOnePathUpAndRemoveDir() error = %v", err)
+		}
+		if FileExists(filepath.Join(dir, "craft-placeholder")) {
+			t.Error("the hoisted directory still exists in the recorded view")
+		}
+		if !FileExists(filepath.Join(dir, "pom.xml")) {
+			t.Error("the hoisted file does not exist in the recorded view")
+		}
+	})
+
+	operations := recorder.Operations()
+	for _, want := range []Operation{
+		{Kind: OperationRemove, Path: filepath.Join(dir, "build.Dockerfile")},
+		{Kind: OperationWrite, Path: filepath.Join(dir, "pom.xml")},
+		{Kind: OperationRemove, Path: filepath.Join(dir, "craft-placeholder")},
+	} {
+		if !hasOperation(operations, want) {
+			t.Errorf("%+v is not recorded: %+v", want, operations)
+		}
+	}
+}
+
+func sorted(paths []string) []string {
+	paths = slices.Clone(paths)
+	slices.Sort(paths)
+	return paths
+}
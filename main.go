@@ -7,8 +7,10 @@ import (
 )
 
 // Embed the entire templates directory.
+// Files starting with '_' are only embedded if they are named explicitly (e.g. python's __init__.py).
 //
 //go:embed templates/*
+//go:embed templates/python/src/PACKAGE/__init__.py.template
 var templatesFS embed.FS
 
 func main() {
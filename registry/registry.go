@@ -8,10 +8,26 @@ import (
 
 var (
 	AllowedOperationsWithLanguages = map[string][]string{
-		"new": {"java", "go", "rust"},
+		"new": {"java", "go", "rust", "python", "typescript", "cpp", "c"},
+		"add": {"java", "go", "rust", "python", "typescript", "cpp", "c"},
+	}
+
+	// LanguageAliases maps alternative names of a language to the language, e.g. 'craft new node'.
+	LanguageAliases = map[string]string{
+		"node": "typescript",
+		"c++":  "cpp",
 	}
 )
 
+// ResolveLanguage returns the language an alias stands for, other languages are returned lower-cased.
+func ResolveLanguage(language string) string {
+	lowerCaseLanguage := strings.ToLower(language)
+	if resolved, ok := LanguageAliases[lowerCaseLanguage]; ok {
+		return resolved
+	}
+	return lowerCaseLanguage
+}
+
 // GetAllowedLanguages returns the supported languages for a specific operation.
 func GetAllowedLanguages(operation string) []string {
 	if languages, exists := AllowedOperationsWithLanguages[operation]; exists {
@@ -20,6 +36,15 @@ func GetAllowedLanguages(operation string) []string {
 	return []string{}
 }
 
+// RegisterLanguage adds a language to an operation, e.g. for templates that are only described by a manifest.
+func RegisterLanguage(operation, language string) {
+	lowerCaseLanguage := strings.ToLower(language)
+	if utils.ContainsStringInsensitive(AllowedOperationsWithLanguages[operation], lowerCaseLanguage) {
+		return
+	}
+	AllowedOperationsWithLanguages[operation] = append(AllowedOperationsWithLanguages[operation], lowerCaseLanguage)
+}
+
 func ValidateOperationAndLanguage(operation, language string) error {
 	lowerCaseLanguage := strings.ToLower(language)
 	allowedLanguages := GetAllowedLanguages(operation)
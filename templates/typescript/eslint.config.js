@@ -0,0 +1,10 @@
+import eslint from "@eslint/js";
+import prettier from "eslint-config-prettier";
+import tseslint from "typescript-eslint";
+
+export default tseslint.config(
+  { ignores: ["dist", "coverage", "node_modules"] },
+  eslint.configs.recommended,
+  tseslint.configs.recommended,
+  prettier,
+);